@@ -0,0 +1,3 @@
+package subscriptionv1
+
+//go:generate protoc -I ../../../proto --go_out=../../../.. --go_opt=module=subscription-service --go-grpc_out=../../../.. --go-grpc_opt=module=subscription-service subscription/v1/subscription.proto
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscription-service/internal/cache"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/service"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+const monthLayout = "01-2006"
+
+var csvHeader = []string{"id", "service_name", "price", "user_id", "start_date", "end_date", "created_at", "updated_at"}
+
+// exportRecord mirrors models.Subscription with dates in the MM-YYYY form
+// accepted by the API, so exported files can be imported again.
+type exportRecord struct {
+	ID          string `json:"id"`
+	ServiceName string `json:"service_name"`
+	Price       int    `json:"price"`
+	UserID      string `json:"user_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// context is the context of the data commands, scoped to the -tenant flag.
+func (a *app) context() context.Context {
+	return tenant.WithID(context.Background(), a.tenant)
+}
+
+// openService wires the service layer for one-off CLI commands. Logs go to
+// stderr so they never mix with command output.
+func (a *app) openService() (*service.SubscriptionService, *sql.DB, error) {
+	appLogger, err := logger.NewWithWriter(os.Stderr, a.cfg.Log.Level, a.cfg.Log.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := repository.NewPostgresDB(a.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo := repository.NewSubscriptionRepository(db)
+	if _, err := useReplicas(a.cfg, repo); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	// Only a shared cache outlives the command and must see its writes.
+	var totals *cache.Totals
+	if a.cfg.Cache.Backend == "redis" {
+		if totals, _, err = newTotalsCache(a.cfg.Cache, repo, appLogger); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+
+	return service.NewSubscriptionService(repo, a.cfg.Validation, totals, appLogger), db, nil
+}
+
+func runImport(a *app, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "file to import, - for stdin")
+	format := fs.String("format", "", "jsonl or csv, detected from the file extension by default")
+	allowOverlap := fs.Bool("allow-overlap", false, "import subscriptions overlapping an existing one to the same service")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	requests, err := readImport(in, detectFormat(*format, *file))
+	if err != nil {
+		return err
+	}
+
+	svc, db, err := a.openService()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := a.context()
+	var failed int
+	for i, req := range requests {
+		req.AllowOverlap = *allowOverlap
+		if _, err := svc.Create(ctx, req); err != nil {
+			fmt.Fprintf(os.Stderr, "record %d: %v\n", i+1, err)
+			failed++
+		}
+	}
+
+	fmt.Printf("imported: %d\nfailed: %d\n", len(requests)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d records were not imported", failed)
+	}
+	return nil
+}
+
+func readImport(in io.Reader, format string) ([]*models.CreateSubscriptionRequest, error) {
+	var requests []*models.CreateSubscriptionRequest
+
+	switch format {
+	case "jsonl":
+		dec := json.NewDecoder(in)
+		for {
+			var req models.CreateSubscriptionRequest
+			if err := dec.Decode(&req); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("invalid json record %d: %w", len(requests)+1, err)
+			}
+			requests = append(requests, &req)
+		}
+	case "csv":
+		r := csv.NewReader(in)
+		header, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv header: %w", err)
+		}
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.TrimSpace(name)] = i
+		}
+		for _, required := range []string{"service_name", "price", "user_id", "start_date"} {
+			if _, ok := columns[required]; !ok {
+				return nil, fmt.Errorf("csv column %q is missing", required)
+			}
+		}
+
+		for line := 2; ; line++ {
+			row, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: %w", line, err)
+			}
+			price, err := strconv.Atoi(row[columns["price"]])
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid price %q", line, row[columns["price"]])
+			}
+			req := &models.CreateSubscriptionRequest{
+				ServiceName: row[columns["service_name"]],
+				Price:       price,
+				UserID:      row[columns["user_id"]],
+				StartDate:   row[columns["start_date"]],
+			}
+			if i, ok := columns["end_date"]; ok {
+				req.EndDate = row[i]
+			}
+			requests = append(requests, req)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	return requests, nil
+}
+
+func runExport(a *app, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	userID := fs.String("user", "", "only export subscriptions of this user")
+	serviceName := fs.String("service", "", "only export subscriptions of this service")
+	format := fs.String("format", "", "jsonl or csv, detected from -out by default")
+	outPath := fs.String("out", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, db, err := a.openService()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	subscriptions, err := svc.List(a.context(), &models.SubscriptionFilter{
+		UserID:      *userID,
+		ServiceName: *serviceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	records := make([]exportRecord, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		records = append(records, toExportRecord(sub))
+	}
+
+	switch detectFormat(*format, *outPath) {
+	case "jsonl":
+		enc := json.NewEncoder(out)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			row := []string{rec.ID, rec.ServiceName, strconv.Itoa(rec.Price), rec.UserID, rec.StartDate, rec.EndDate, rec.CreatedAt, rec.UpdatedAt}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+}
+
+func toExportRecord(sub models.Subscription) exportRecord {
+	rec := exportRecord{
+		ID:          sub.ID.String(),
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		UserID:      sub.UserID.String(),
+		StartDate:   sub.StartDate.Format(monthLayout),
+		CreatedAt:   sub.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   sub.UpdatedAt.Format(time.RFC3339),
+	}
+	if sub.EndDate != nil {
+		rec.EndDate = sub.EndDate.Format(monthLayout)
+	}
+	return rec
+}
+
+func runReport(a *app, args []string) error {
+	if len(args) == 0 || args[0] != "total" {
+		return errors.New("usage: report total [-user ID] [-service NAME] [-from MM-YYYY] [-to MM-YYYY]")
+	}
+
+	fs := flag.NewFlagSet("report total", flag.ContinueOnError)
+	userID := fs.String("user", "", "user id")
+	serviceName := fs.String("service", "", "service name")
+	from := fs.String("from", "", "first month, MM-YYYY")
+	to := fs.String("to", "", "last month, MM-YYYY")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	svc, db, err := a.openService()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	total, err := svc.GetTotalCost(a.context(), &models.SubscriptionFilter{
+		UserID:      *userID,
+		ServiceName: *serviceName,
+		StartMonth:  *from,
+		EndMonth:    *to,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("total: %d\n", total)
+	return nil
+}
+
+var seedServices = []struct {
+	name  string
+	price int
+}{
+	{"Netflix", 999},
+	{"Spotify", 299},
+	{"Yandex Plus", 400},
+	{"YouTube Premium", 299},
+	{"Apple Music", 169},
+}
+
+func runSeed(a *app, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	users := fs.Int("users", 5, "number of users to create")
+	perUser := fs.Int("per-user", 3, "subscriptions per user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, db, err := a.openService()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := a.context()
+	now := time.Now()
+	var created int
+	for u := 0; u < *users; u++ {
+		userID := uuid.NewString()
+		services := rand.Perm(len(seedServices))
+		for i := 0; i < *perUser; i++ {
+			s := seedServices[services[i%len(services)]]
+			start := now.AddDate(0, -rand.Intn(24), 0)
+			req := &models.CreateSubscriptionRequest{
+				ServiceName: s.name,
+				Price:       s.price,
+				UserID:      userID,
+				StartDate:   start.Format(monthLayout),
+
+				AllowOverlap: i >= len(services),
+			}
+			if rand.Intn(3) == 0 {
+				req.EndDate = start.AddDate(0, 1+rand.Intn(12), 0).Format(monthLayout)
+			}
+			if _, err := svc.Create(ctx, req); err != nil {
+				return err
+			}
+			created++
+		}
+	}
+
+	fmt.Printf("seeded: %d\n", created)
+	return nil
+}
+
+func detectFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return "csv"
+	}
+	return "jsonl"
+}
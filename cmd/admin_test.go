@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	input := `id,service_name,price,user_id,start_date,end_date
+,Netflix,999,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,
+,Spotify,299,60601fee-2bf1-4721-ae6f-7636e79a0cba,01-2025,12-2025
+`
+
+	requests, err := readImport(strings.NewReader(input), "csv")
+	if err != nil {
+		t.Fatalf("Failed to read csv: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(requests))
+	}
+	if requests[1].ServiceName != "Spotify" || requests[1].Price != 299 || requests[1].EndDate != "12-2025" {
+		t.Errorf("Unexpected record: %+v", requests[1])
+	}
+}
+
+func TestReadImportJSONLines(t *testing.T) {
+	input := `{"service_name":"Netflix","price":999,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}
+{"service_name":"Spotify","price":299,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"01-2025"}
+`
+
+	requests, err := readImport(strings.NewReader(input), "jsonl")
+	if err != nil {
+		t.Fatalf("Failed to read json lines: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(requests))
+	}
+}
+
+func TestReadImportCSVMissingColumn(t *testing.T) {
+	if _, err := readImport(strings.NewReader("service_name,price\nNetflix,999\n"), "csv"); err == nil {
+		t.Error("Expected error for missing columns")
+	}
+}
@@ -1,72 +1,86 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"sort"
 
 	"subscription-service/internal/config"
-	"subscription-service/internal/handler"
-	"subscription-service/internal/logger"
-	"subscription-service/internal/repository"
-	"subscription-service/internal/service"
+	"subscription-service/internal/tenant"
 )
 
+type app struct {
+	configPath string
+	tenant     string
+	cfg        *config.Config
+}
+
+type command struct {
+	usage string
+	run   func(a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"serve":   {usage: "serve (default)", run: runServe},
+	"migrate": {usage: "migrate up|down|goto|status|force", run: runMigrate},
+	"import":  {usage: "import -file PATH [-format jsonl|csv] [-allow-overlap]", run: runImport},
+	"export":  {usage: "export [-user ID] [-service NAME] [-format jsonl|csv] [-out PATH]", run: runExport},
+	"report":  {usage: "report total [-user ID] [-service NAME] [-from MM-YYYY] [-to MM-YYYY]", run: runReport},
+	"seed":    {usage: "seed [-users N] [-per-user N]", run: runSeed},
+}
+
 func main() {
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
+	configPath := flag.String("config", defaultConfigPath(), "path to the YAML configuration file")
+	tenantID := flag.String("tenant", tenant.Default, "tenant the data commands act for")
+	flag.Usage = usage
+	flag.Parse()
 
-	appLogger, err := logger.New(cfg.LogLevel)
-	if err != nil {
-		log.Fatalf("Failed to init logger: %v", err)
+	if !tenant.Valid(*tenantID) {
+		log.Fatalf("Invalid tenant id %q", *tenantID)
 	}
-	defer appLogger.Sync()
 
-	db, err := repository.NewPostgresDB(cfg)
-	if err != nil {
-		appLogger.Fatal("Failed to connect to database", "error", err)
+	name, args := "serve", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
-	defer db.Close()
 
-	if err := repository.RunMigrations(cfg); err != nil {
-		appLogger.Fatal("Failed to run migrations", "error", err)
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
 
-	subscriptionRepo := repository.NewSubscriptionRepository(db)
-	subscriptionService := service.NewSubscriptionService(subscriptionRepo, appLogger)
-	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService, appLogger)
-
-	router := handler.SetupRouter(subscriptionHandler, appLogger)
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.ServerPort),
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	if err := cmd.run(&app{configPath: *configPath, tenant: *tenantID, cfg: cfg}, args); err != nil {
+		log.Fatalf("%s failed: %v", name, err)
 	}
+}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Fatal("Server failed", "error", err)
-		}
-	}()
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-config PATH] [-tenant ID] <command> [arguments]\n\nCommands:\n", os.Args[0])
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", commands[name].usage)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+	flag.PrintDefaults()
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		appLogger.Fatal("Server forced to shutdown", "error", err)
+func defaultConfigPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
 	}
+	return config.DefaultPath
 }
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"subscription-service/internal/archive"
+	"subscription-service/internal/cache"
+	"subscription-service/internal/config"
+	"subscription-service/internal/events"
+	"subscription-service/internal/graphqlapi"
+	"subscription-service/internal/grpcserver"
+	"subscription-service/internal/handler"
+	"subscription-service/internal/health"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/reminder"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/service"
+	"subscription-service/internal/tenant"
+	"subscription-service/internal/tracing"
+	"subscription-service/internal/webhook"
+
+	"google.golang.org/grpc"
+)
+
+func runServe(a *app, args []string) error {
+	if len(args) > 0 {
+		return errors.New("serve takes no arguments")
+	}
+	cfg := a.cfg
+
+	appLogger, err := logger.New(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return fmt.Errorf("failed to init logger: %w", err)
+	}
+	defer appLogger.Sync()
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		appLogger.Fatal("Failed to init tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			appLogger.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	db, err := repository.NewPostgresDB(cfg)
+	if err != nil {
+		appLogger.Fatal("Failed to connect to database", "error", err)
+	}
+	defer db.Close()
+
+	if cfg.Database.AutoMigrate {
+		if err := repository.RunMigrations(cfg); err != nil {
+			appLogger.Fatal("Failed to run migrations", "error", err)
+		}
+	}
+
+	// The workers that act for every tenant need a role the row-level
+	// security policies do not apply to.
+	workerDB := db
+	if cfg.Tenancy.RowLevelSecurity {
+		if workerDB, err = repository.NewWorkerDB(cfg); err != nil {
+			appLogger.Fatal("Failed to connect to database", "error", err)
+		}
+		defer workerDB.Close()
+	}
+
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	closeReplicas, err := useReplicas(cfg, subscriptionRepo)
+	if err != nil {
+		appLogger.Fatal("Failed to open database replicas", "error", err)
+	}
+	defer closeReplicas()
+	totals, closeCache, err := newTotalsCache(cfg.Cache, subscriptionRepo, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to init cache", "error", err)
+	}
+	defer closeCache()
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, cfg.Validation, totals, appLogger)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService, appLogger)
+
+	migrationCheck, err := repository.MigrationCheck(db)
+	if err != nil {
+		appLogger.Fatal("Failed to read migrations", "error", err)
+	}
+
+	checker := health.NewChecker(2 * time.Second)
+	checker.Add("database", db.PingContext)
+	checker.Add("migrations", migrationCheck)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var workers sync.WaitGroup
+
+	if cfg.Reminders.Enabled {
+		notifiers, err := reminder.NewNotifiers(cfg.Reminders, appLogger)
+		if err != nil {
+			appLogger.Fatal("Failed to init reminder channels", "error", err)
+		}
+		scheduler := reminder.NewScheduler(repository.NewReminderRepository(workerDB), notifiers, cfg.Reminders, appLogger)
+		checker.Add("worker:reminders", scheduler.Worker().Check)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			scheduler.Run(backgroundCtx)
+		}()
+	}
+
+	archiveStore, err := archive.NewStore(cfg.Archive)
+	if err != nil {
+		appLogger.Fatal("Failed to init archive store", "error", err)
+	}
+	archiver := archive.NewArchiver(subscriptionRepo, archiveStore, cfg.Archive, appLogger)
+	if cfg.Archive.Enabled {
+		workerRepo := subscriptionRepo
+		if workerDB != db {
+			workerRepo = repository.NewSubscriptionRepository(workerDB)
+			if totals != nil {
+				workerRepo.OnWrite(totals.Invalidate)
+			}
+		}
+		archiveWorker := archive.NewArchiver(workerRepo, archiveStore, cfg.Archive, appLogger)
+		checker.Add("worker:archive", archiveWorker.Worker().Check)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			archiveWorker.Run(backgroundCtx)
+		}()
+	}
+
+	healthHandler := handler.NewHealthHandler(checker)
+
+	store := config.NewStore(a.configPath, cfg)
+	store.OnReload(func(cfg *config.Config) {
+		appLogger.SetLevel(cfg.Log.Level)
+	})
+
+	webhookGuard, err := webhook.NewGuard(cfg.Webhooks.AllowedNetworks)
+	if err != nil {
+		appLogger.Fatal("Failed to init webhooks", "error", err)
+	}
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookHandler := handler.NewWebhookHandler(service.NewWebhookService(webhookRepo, webhookGuard, appLogger), appLogger)
+
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhook.NewDispatcher(repository.NewWebhookRepository(workerDB), cfg.Webhooks, webhookGuard, appLogger)
+		checker.Add("worker:webhooks", dispatcher.Worker().Check)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(backgroundCtx)
+		}()
+	}
+
+	broker := events.NewBroker(repository.NewEventRepository(workerDB), cfg.Events.PollInterval, appLogger)
+	checker.Add("worker:events", broker.Worker().Check)
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		broker.Run(backgroundCtx)
+	}()
+
+	tenants := tenant.NewResolver(cfg.Tenancy)
+
+	handlers := handler.Handlers{
+		Subscriptions: subscriptionHandler,
+		Health:        healthHandler,
+		Webhooks:      webhookHandler,
+		Events:        handler.NewEventsHandler(broker, appLogger),
+		Budgets:       handler.NewBudgetHandler(service.NewBudgetService(repository.NewBudgetRepository(db), appLogger), appLogger),
+		Archives:      handler.NewArchiveHandler(archiver, appLogger),
+		Privacy:       handler.NewPrivacyHandler(service.NewPrivacyService(subscriptionRepo, archiver, appLogger), appLogger),
+		Tenants:       tenants,
+	}
+	if cfg.GraphQL.Enabled {
+		executor, err := graphqlapi.NewExecutor(subscriptionService)
+		if err != nil {
+			appLogger.Fatal("Failed to init GraphQL", "error", err)
+		}
+		handlers.GraphQL = handler.NewGraphQLHandler(executor, store, appLogger)
+	}
+
+	router := handler.SetupRouter(handlers, store, appLogger)
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	// Event streams only end with their subscription, so they are closed as
+	// soon as the shutdown starts rather than holding it until the timeout.
+	srv.RegisterOnShutdown(broker.Close)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			appLogger.Fatal("Server failed", "error", err)
+		}
+	}()
+
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			appLogger.Fatal("Failed to listen for gRPC", "error", err)
+		}
+		grpcSrv = grpcserver.New(subscriptionService, tenants, appLogger)
+
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				appLogger.Fatal("gRPC server failed", "error", err)
+			}
+		}()
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	go store.Watch(backgroundCtx, cfg.Reload.WatchInterval, func() {
+		select {
+		case reload <- syscall.SIGHUP:
+		default:
+		}
+	})
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	for running := true; running; {
+		select {
+		case <-reload:
+			reloadConfig(store, appLogger)
+		case <-quit:
+			running = false
+		}
+	}
+
+	// Readiness fails from now on; keep serving for the delay so that probes
+	// see it and traffic is drained before the listeners close.
+	checker.StartShutdown()
+	if cfg.Server.ShutdownDelay > 0 {
+		appLogger.Info("Shutting down", "delay", cfg.Server.ShutdownDelay)
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		appLogger.Error("Server forced to shutdown", "error", err)
+	}
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
+
+	stopBackground()
+	workers.Wait()
+
+	return nil
+}
+
+// stopGRPC waits for in-flight RPCs to finish until ctx expires, then closes
+// the remaining connections.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+func reloadConfig(store *config.Store, appLogger *logger.Logger) {
+	ignored, err := store.Reload()
+	if err != nil {
+		appLogger.Error("Failed to reload config, keeping previous", "error", err)
+		return
+	}
+	if len(ignored) > 0 {
+		appLogger.Warn("Config reloaded, some changes require a restart", "sections", ignored)
+		return
+	}
+	appLogger.Info("Config reloaded")
+}
+
+// useReplicas routes the reads of repo to the configured replicas, if any.
+func useReplicas(cfg *config.Config, repo *repository.SubscriptionRepository) (func(), error) {
+	if len(cfg.Database.Replicas) == 0 {
+		return func() {}, nil
+	}
+
+	dbs, err := repository.NewReplicaDBs(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := repository.NewReplicaSet(dbs, cfg.Database.ReadYourWritesWindow)
+	repo.UseReplicas(replicas)
+	return func() { replicas.Close() }, nil
+}
+
+// newTotalsCache builds the cache of computed totals and subscribes it to the
+// writes of repo. It returns a nil cache when caching is disabled.
+func newTotalsCache(cfg config.CacheConfig, repo *repository.SubscriptionRepository, log *logger.Logger) (*cache.Totals, func(), error) {
+	backend, err := cache.New(cfg)
+	if err != nil || backend == nil {
+		return nil, func() {}, err
+	}
+
+	totals := cache.NewTotals(backend, log)
+	repo.OnWrite(totals.Invalidate)
+	return totals, func() { backend.Close() }, nil
+}
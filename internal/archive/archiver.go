@@ -0,0 +1,284 @@
+// Package archive moves subscriptions that ended long ago out of the
+// subscriptions table into compressed JSON-lines files.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/health"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("archive not found")
+	ErrRestored = errors.New("archive already restored")
+)
+
+// Archiver periodically archives the subscriptions that ended more than the
+// configured number of months ago, in batches of one file per tenant, and
+// restores archives on demand. Totals keep counting archived subscriptions
+// through the monthly aggregates the repository maintains.
+type Archiver struct {
+	repo   *repository.SubscriptionRepository
+	store  Store
+	cfg    config.ArchiveConfig
+	logger *logger.Logger
+	worker *health.Worker
+	now    func() time.Time
+}
+
+func NewArchiver(repo *repository.SubscriptionRepository, store Store, cfg config.ArchiveConfig, logger *logger.Logger) *Archiver {
+	return &Archiver{
+		repo:   repo,
+		store:  store,
+		cfg:    cfg,
+		logger: logger,
+		worker: health.NewWorker(3 * cfg.Interval),
+		now:    time.Now,
+	}
+}
+
+// Worker exposes the archiver heartbeat for readiness checks.
+func (a *Archiver) Worker() *health.Worker {
+	return a.worker
+}
+
+func (a *Archiver) Run(ctx context.Context) {
+	defer a.worker.Stop()
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			a.logger.Error("archive run failed", "error", err)
+		}
+		a.worker.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Archiver) RunOnce(ctx context.Context) error {
+	endedBefore := cutoff(a.now(), a.cfg.AfterMonths)
+
+	tenants, err := a.repo.ArchiveTenants(ctx, endedBefore)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range tenants {
+		tenantCtx := tenant.WithID(ctx, id)
+		for {
+			archive, err := a.repo.Archive(tenantCtx, endedBefore, a.cfg.BatchSize, func(records []models.ArchivedSubscription) (string, error) {
+				return a.write(tenantCtx, id, records)
+			})
+			if err != nil {
+				return err
+			}
+			if archive == nil {
+				break
+			}
+			a.logger.Info("subscriptions archived", "tenant", id, "archive", archive.Name, "subscriptions", archive.Subscriptions)
+			if archive.Subscriptions < a.cfg.BatchSize {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *Archiver) write(ctx context.Context, tenantID string, records []models.ArchivedSubscription) (string, error) {
+	data, err := encode(records)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s/subscriptions-%s-%s.jsonl.gz", tenantID, a.now().UTC().Format("20060102T150405Z"), uuid.NewString()[:8])
+	if err := a.store.Put(ctx, name, data); err != nil {
+		return "", fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// List returns the archives of the tenant of ctx, newest first.
+func (a *Archiver) List(ctx context.Context) ([]models.Archive, error) {
+	return a.repo.ListArchives(ctx)
+}
+
+// Restore moves the subscriptions of an archive of the tenant of ctx back
+// into the subscriptions table.
+func (a *Archiver) Restore(ctx context.Context, id uuid.UUID) (*models.Archive, error) {
+	archive, err := a.repo.GetArchive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return nil, ErrNotFound
+	}
+	if archive.RestoredAt != nil {
+		return nil, ErrRestored
+	}
+
+	records, err := a.read(ctx, archive.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.repo.RestoreArchive(ctx, archive, records); err != nil {
+		if errors.Is(err, repository.ErrArchiveRestored) {
+			return nil, ErrRestored
+		}
+		return nil, err
+	}
+	return a.repo.GetArchive(ctx, id)
+}
+
+// UserRecords returns the archived subscriptions of a user of the tenant of
+// ctx. Restored archives are skipped, their subscriptions are live again.
+func (a *Archiver) UserRecords(ctx context.Context, userID uuid.UUID) ([]models.ArchivedSubscription, error) {
+	archives, err := a.repo.ListArchives(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.ArchivedSubscription
+	for _, archive := range archives {
+		if archive.RestoredAt != nil {
+			continue
+		}
+		records, err := a.read(ctx, archive.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			if rec.UserID == userID {
+				found = append(found, rec)
+			}
+		}
+	}
+	return found, nil
+}
+
+// EraseUser rewrites every archive of the tenant of ctx holding records of
+// a user without them, restored ones included, and returns how many were
+// removed from archives not restored yet.
+func (a *Archiver) EraseUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	archives, err := a.repo.ListArchives(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var erased int
+	for _, archive := range archives {
+		records, err := a.read(ctx, archive.Name)
+		if err != nil {
+			return erased, err
+		}
+		kept, removed := withoutUser(records, userID)
+		if removed == 0 {
+			continue
+		}
+
+		data, err := encode(kept)
+		if err != nil {
+			return erased, err
+		}
+		if err := a.store.Put(ctx, archive.Name, data); err != nil {
+			return erased, fmt.Errorf("failed to write archive %s: %w", archive.Name, err)
+		}
+		if err := a.repo.SetArchiveSize(ctx, archive.ID, len(kept)); err != nil {
+			return erased, err
+		}
+		if archive.RestoredAt == nil {
+			erased += removed
+		}
+		a.logger.Info("user erased from archive", "archive", archive.Name, "subscriptions", removed)
+	}
+	return erased, nil
+}
+
+func (a *Archiver) read(ctx context.Context, name string) ([]models.ArchivedSubscription, error) {
+	data, err := a.store.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", name, err)
+	}
+	records, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode archive %s: %w", name, err)
+	}
+	return records, nil
+}
+
+// withoutUser returns records without the ones of userID and how many those
+// were.
+func withoutUser(records []models.ArchivedSubscription, userID uuid.UUID) ([]models.ArchivedSubscription, int) {
+	kept := make([]models.ArchivedSubscription, 0, len(records))
+	for _, rec := range records {
+		if rec.UserID != userID {
+			kept = append(kept, rec)
+		}
+	}
+	return kept, len(records) - len(kept)
+}
+
+// cutoff is the first day of the month months before the one of now.
+// Subscriptions that ended before it are archived.
+func cutoff(now time.Time, months int) time.Time {
+	return time.Date(now.Year(), now.Month()-time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func encode(records []models.ArchivedSubscription) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte) ([]models.ArchivedSubscription, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var records []models.ArchivedSubscription
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec models.ArchivedSubscription
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
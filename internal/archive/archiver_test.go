@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	end := time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC)
+	records := []models.ArchivedSubscription{{
+		Subscription: models.Subscription{
+			ID:          uuid.New(),
+			ServiceName: "Netflix",
+			Price:       400,
+			UserID:      uuid.New(),
+			StartDate:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:     &end,
+		},
+		PriceChanges: []models.PriceChange{{ID: uuid.New(), EffectiveDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Price: 500}},
+	}}
+
+	data, err := encode(records)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, err := decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	if len(decoded) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(decoded))
+	}
+	got := decoded[0]
+	if got.ID != records[0].ID || got.Price != 400 || !got.EndDate.Equal(end) {
+		t.Errorf("Expected the archived subscription back, got %+v", got.Subscription)
+	}
+	if len(got.PriceChanges) != 1 || got.PriceChanges[0].Price != 500 {
+		t.Errorf("Expected the price change back, got %+v", got.PriceChanges)
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	if got := cutoff(now, 12); !got.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2025-03-01, got %s", got)
+	}
+	if got := cutoff(now, 4); !got.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2025-11-01, got %s", got)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store := &LocalStore{dir: t.TempDir()}
+
+	if err := store.Put(ctx, "acme/archive.jsonl.gz", []byte("data")); err != nil {
+		t.Fatalf("Failed to put: %v", err)
+	}
+	data, err := store.Get(ctx, "acme/archive.jsonl.gz")
+	if err != nil || string(data) != "data" {
+		t.Errorf("Expected the stored data, got %q, %v", data, err)
+	}
+
+	if _, err := store.Get(ctx, "acme/missing.jsonl.gz"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestWithoutUser(t *testing.T) {
+	user, other := uuid.New(), uuid.New()
+	records := []models.ArchivedSubscription{
+		{Subscription: models.Subscription{ID: uuid.New(), UserID: user}},
+		{Subscription: models.Subscription{ID: uuid.New(), UserID: other}},
+		{Subscription: models.Subscription{ID: uuid.New(), UserID: user}},
+	}
+
+	kept, removed := withoutUser(records, user)
+	if removed != 2 {
+		t.Errorf("Expected 2 records removed, got %d", removed)
+	}
+	if len(kept) != 1 || kept[0].UserID != other {
+		t.Errorf("Expected only the other user's record to be kept, got %+v", kept)
+	}
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"subscription-service/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var ErrFileNotFound = errors.New("archive file not found")
+
+// Store keeps archive files by name. Names are slash-separated paths.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// NewStore returns the store of the configured backend.
+func NewStore(cfg config.ArchiveConfig) (Store, error) {
+	switch cfg.Backend {
+	case "local":
+		return &LocalStore{dir: cfg.Dir}, nil
+	case "s3":
+		client, err := minio.New(cfg.S3.Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.S3.AccessKey, cfg.S3.SecretKey, ""),
+			Secure: cfg.S3.UseSSL,
+			Region: cfg.S3.Region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create s3 client: %w", err)
+		}
+		return &S3Store{client: client, bucket: cfg.S3.Bucket, prefix: cfg.S3.Prefix}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive backend %q", cfg.Backend)
+	}
+}
+
+// LocalStore keeps archives as files under a directory.
+type LocalStore struct {
+	dir string
+}
+
+// Put writes data to a temporary file first, so that a crash never leaves a
+// truncated archive behind.
+func (s *LocalStore) Put(_ context.Context, name string, data []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *LocalStore) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return data, err
+}
+
+// S3Store keeps archives as objects of a bucket of an S3-compatible store.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func (s *S3Store) Put(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.prefix+name, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/gzip"})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, name string) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return nil, ErrFileNotFound
+	}
+	return data, err
+}
@@ -0,0 +1,36 @@
+// Package cache keeps computed subscription totals so that repeated queries
+// skip the aggregation, and drops them when a write changes their inputs.
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"subscription-service/internal/config"
+)
+
+// Backend stores values with the TTL it was created with, and generation
+// counters that never expire on their own. A missing key is not an error.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+	// Generation returns the counter of tag, zero when it was never bumped.
+	Generation(ctx context.Context, tag string) (uint64, error)
+	// Bump increments the counters of tags.
+	Bump(ctx context.Context, tags ...string) error
+	Close() error
+}
+
+// New returns the backend selected by cfg, or nil for "none".
+func New(cfg config.CacheConfig) (Backend, error) {
+	switch cfg.Backend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return NewMemory(cfg.Size, cfg.TTL), nil
+	case "redis":
+		return NewRedis(cfg.Redis, cfg.TTL), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
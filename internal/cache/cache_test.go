@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+func newTestTotals(t *testing.T) *Totals {
+	t.Helper()
+	log, err := logger.NewWithWriter(io.Discard, "error", "text")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return NewTotals(NewMemory(100, time.Minute), log)
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2, time.Minute)
+
+	m.Set(ctx, "a", []byte("1"))
+	m.Set(ctx, "b", []byte("2"))
+	m.Get(ctx, "a")
+	m.Set(ctx, "c", []byte("3"))
+
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok, _ := m.Get(ctx, "a"); !ok {
+		t.Error("Expected a to be kept")
+	}
+}
+
+func TestMemoryExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2, time.Minute)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	m.Set(ctx, "a", []byte("1"))
+	now = now.Add(2 * time.Minute)
+
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Error("Expected a to be expired")
+	}
+}
+
+func TestMemoryGenerationsNeverGoBack(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2, time.Minute)
+
+	m.Bump(ctx, "a")
+	before, _ := m.Generation(ctx, "a")
+	m.Bump(ctx, "b", "c")
+
+	after, _ := m.Generation(ctx, "a")
+	if after < before {
+		t.Errorf("Expected generation of a to stay at least %d, got %d", before, after)
+	}
+	if gen, _ := m.Generation(ctx, "b"); gen == 0 {
+		t.Error("Expected b to be bumped")
+	}
+}
+
+func TestTotalsInvalidatesAffectedFilters(t *testing.T) {
+	ctx := context.Background()
+	totals := newTestTotals(t)
+	user := uuid.New()
+
+	calls := 0
+	compute := func(value int) func() (int, error) {
+		return func() (int, error) {
+			calls++
+			return value, nil
+		}
+	}
+	byUser := &models.SubscriptionFilter{UserID: user.String()}
+	byOther := &models.SubscriptionFilter{UserID: uuid.NewString()}
+
+	totals.Total(ctx, byUser, compute(100))
+	totals.Total(ctx, byOther, compute(200))
+	if got, _ := totals.Total(ctx, byUser, compute(0)); got != 100 || calls != 2 {
+		t.Fatalf("Expected a cached 100 after 2 computations, got %d after %d", got, calls)
+	}
+
+	totals.Invalidate(ctx, []models.Subscription{{UserID: user, ServiceName: "Netflix", TenantID: tenant.Default}})
+
+	if got, _ := totals.Total(ctx, byUser, compute(150)); got != 150 {
+		t.Errorf("Expected the user's total to be recomputed, got %d", got)
+	}
+	if got, _ := totals.Total(ctx, byOther, compute(0)); got != 200 {
+		t.Errorf("Expected the other user's total to stay cached, got %d", got)
+	}
+	if got, _ := totals.Total(ctx, &models.SubscriptionFilter{UserID: user.String(), ServiceName: "Spotify"}, compute(7)); got != 7 || calls != 4 {
+		t.Errorf("Expected an uncached filter to be computed, got %d after %d", got, calls)
+	}
+}
+
+func TestFilterTagNormalizesUserID(t *testing.T) {
+	id := uuid.New()
+	upper := filterTag(tenant.Default, strings.ToUpper(id.String()), "Netflix")
+	if upper != filterTag(tenant.Default, id.String(), "Netflix") {
+		t.Errorf("Expected the same tag for both cases of a user id, got %s", upper)
+	}
+	if filterTag(tenant.Default, "", "Netflix") == filterTag(tenant.Default, "", "netflix") {
+		t.Error("Expected service names to be compared exactly")
+	}
+	if filterTag("acme", id.String(), "") == filterTag("globex", id.String(), "") {
+		t.Error("Expected tenants to have distinct tags")
+	}
+}
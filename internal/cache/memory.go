@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process LRU backend. Counters are kept apart from the
+// entries so that evicting an entry never resets a generation.
+type Memory struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*list.Element
+	order   *list.List
+
+	gens map[string]uint64
+	// seq is the last generation handed out by Bump and floor the value of
+	// every counter forgotten when gens outgrew size. Counters only move up,
+	// so an entry stored under a forgotten counter can never match again.
+	seq   uint64
+	floor uint64
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemory(size int, ttl time.Duration) *Memory {
+	return &Memory{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		gens:    make(map[string]uint64),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if m.now().After(entry.expires) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expires := m.now().Add(m.ttl)
+	if el, ok := m.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value, entry.expires = value, expires
+		m.order.MoveToFront(el)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+func (m *Memory) Generation(_ context.Context, tag string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gen, ok := m.gens[tag]; ok {
+		return gen, nil
+	}
+	return m.floor, nil
+}
+
+func (m *Memory) Bump(_ context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.gens)+len(tags) > m.size {
+		m.gens = make(map[string]uint64)
+		m.floor = m.seq
+	}
+	for _, tag := range tags {
+		m.seq++
+		m.gens[tag] = m.seq
+	}
+	return nil
+}
+
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
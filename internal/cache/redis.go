@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"subscription-service/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a backend on a Redis-compatible server, shared by every instance
+// of the service so that a write on one instance invalidates all of them.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedis(cfg config.RedisConfig, ttl time.Duration) *Redis {
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		prefix: cfg.KeyPrefix,
+		ttl:    ttl,
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte) error {
+	return r.client.Set(ctx, r.prefix+key, value, r.ttl).Err()
+}
+
+func (r *Redis) Generation(ctx context.Context, tag string) (uint64, error) {
+	gen, err := r.client.Get(ctx, r.prefix+"gen:"+tag).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+func (r *Redis) Bump(ctx context.Context, tags ...string) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.Incr(ctx, r.prefix+"gen:"+tag)
+		}
+		return nil
+	})
+	return err
+}
+
+// Ping checks that the server is reachable, for the readiness probe.
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	kindTotal   = "total"
+	kindMonthly = "monthly"
+
+	anyValue = "*"
+)
+
+// Totals caches total costs and monthly breakdowns keyed by their normalized
+// filter. Every entry belongs to the tag of its user and service filters, and
+// its key embeds the tag's generation: a write bumps the generations of the
+// tags it can affect, so stale entries are never read again and simply age
+// out. Bumping after the commit also makes a computation racing with a write
+// store its result under the old generation, where nobody looks.
+//
+// A nil *Totals computes every value.
+type Totals struct {
+	backend Backend
+	logger  *logger.Logger
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+	errors metric.Int64Counter
+}
+
+func NewTotals(backend Backend, logger *logger.Logger) *Totals {
+	meter := otel.Meter("subscription-service/internal/cache")
+	hits, _ := meter.Int64Counter("cache.hits", metric.WithDescription("Cached totals served without querying the database"))
+	misses, _ := meter.Int64Counter("cache.misses", metric.WithDescription("Totals computed because no cached value was found"))
+	errs, _ := meter.Int64Counter("cache.errors", metric.WithDescription("Failed cache backend calls"))
+
+	return &Totals{
+		backend: backend,
+		logger:  logger,
+		hits:    hits,
+		misses:  misses,
+		errors:  errs,
+	}
+}
+
+// Total returns the cached total cost for filter, calling compute on a miss.
+func (t *Totals) Total(ctx context.Context, filter *models.SubscriptionFilter, compute func() (int, error)) (int, error) {
+	if t == nil {
+		return compute()
+	}
+	rest := filter.StartMonth + "|" + filter.EndMonth
+	return get(ctx, t, kindTotal, filter, rest, compute)
+}
+
+// MonthlyBreakdown returns the cached breakdown of filter over [from, to],
+// calling compute on a miss.
+func (t *Totals) MonthlyBreakdown(ctx context.Context, filter *models.SubscriptionFilter, from, to time.Time, compute func() ([]models.MonthlyCost, error)) ([]models.MonthlyCost, error) {
+	if t == nil {
+		return compute()
+	}
+	rest := from.Format("01-2006") + "|" + to.Format("01-2006")
+	return get(ctx, t, kindMonthly, filter, rest, compute)
+}
+
+func get[T any](ctx context.Context, t *Totals, kind string, filter *models.SubscriptionFilter, rest string, compute func() (T, error)) (T, error) {
+	attrs := metric.WithAttributes(attribute.String("kind", kind))
+	tag := filterTag(tenant.FromContext(ctx), filter.UserID, filter.ServiceName)
+
+	gen, err := t.backend.Generation(ctx, tag)
+	if err != nil {
+		t.failed(ctx, "failed to read cache generation", err)
+		return compute()
+	}
+	key := kind + "|" + tag + "|" + strconv.FormatUint(gen, 10) + "|" + rest
+
+	if data, ok, err := t.backend.Get(ctx, key); err != nil {
+		t.failed(ctx, "failed to read cache", err)
+	} else if ok {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			t.hits.Add(ctx, 1, attrs)
+			return value, nil
+		}
+	}
+
+	t.misses.Add(ctx, 1, attrs)
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = t.backend.Set(ctx, key, data)
+	}
+	if err != nil {
+		t.failed(ctx, "failed to write cache", err)
+	}
+	return value, nil
+}
+
+// Invalidate drops the cached values the affected subscriptions count
+// towards: those of their tenant filtered by their user, their service, both
+// or neither. It has the signature of repository.WriteHook.
+func (t *Totals) Invalidate(ctx context.Context, affected []models.Subscription) {
+	if t == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var tags []string
+	for _, sub := range affected {
+		user := sub.UserID.String()
+		for _, tag := range []string{
+			filterTag(sub.TenantID, user, sub.ServiceName),
+			filterTag(sub.TenantID, user, ""),
+			filterTag(sub.TenantID, "", sub.ServiceName),
+			filterTag(sub.TenantID, "", ""),
+		} {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	// The write has committed: invalidate even if the request was canceled.
+	if err := t.backend.Bump(context.WithoutCancel(ctx), tags...); err != nil {
+		t.failed(ctx, "failed to invalidate cache", err)
+	}
+}
+
+func (t *Totals) failed(ctx context.Context, msg string, err error) {
+	t.errors.Add(ctx, 1)
+	logger.FromContext(ctx, t.logger).Error(msg, "error", err)
+}
+
+// filterTag identifies the tenant and the user and service filters of a
+// query. User IDs are compared in canonical form; service names exactly, as
+// the queries do.
+func filterTag(tenantID, userID, serviceName string) string {
+	if userID == "" {
+		userID = anyValue
+	} else if id, err := uuid.Parse(userID); err == nil {
+		userID = id.String()
+	}
+	if serviceName == "" {
+		serviceName = anyValue
+	} else {
+		serviceName = strconv.Quote(serviceName)
+	}
+	return "t=" + tenantID + ",u=" + userID + ",s=" + serviceName
+}
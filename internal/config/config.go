@@ -1,46 +1,824 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+const DefaultPath = "configs/config.yaml"
+
+// FeatureServiceSuggestions toggles the service name autocompletion endpoint.
+const FeatureServiceSuggestions = "service_suggestions"
+
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	ServerPort string
-	LogLevel   string
+	Server   ServerConfig   `yaml:"server"`
+	GRPC     GRPCConfig     `yaml:"grpc"`
+	GraphQL  GraphQLConfig  `yaml:"graphql"`
+	Database DatabaseConfig `yaml:"database"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Features  map[string]bool `yaml:"features"`
+	Reload    ReloadConfig    `yaml:"reload"`
+
+	Reminders RemindersConfig `yaml:"reminders"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks"`
+	Events    EventsConfig    `yaml:"events"`
+	Archive   ArchiveConfig   `yaml:"archive"`
+
+	Validation ValidationConfig `yaml:"validation"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Tenancy    TenancyConfig    `yaml:"tenancy"`
+}
+
+type ServerConfig struct {
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// ShutdownDelay is how long readiness reports the shutdown before the
+	// listeners close, so that load balancers stop routing to the instance.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+}
+
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	MaxDepth      int  `yaml:"max_depth"`
+	MaxComplexity int  `yaml:"max_complexity"`
 }
 
-func Load() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
+type DatabaseConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
+
+	// Replicas are DSNs of read-only replicas serving queries. Reads that
+	// may depend on a write made less than ReadYourWritesWindow ago still go
+	// to the primary.
+	Replicas             []string      `yaml:"replicas"`
+	ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type TracingConfig struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	File         string `yaml:"file"`
+}
+
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type ReloadConfig struct {
+	WatchInterval time.Duration `yaml:"watch_interval"`
+}
+
+type RemindersConfig struct {
+	Enabled      bool                 `yaml:"enabled"`
+	Interval     time.Duration        `yaml:"interval"`
+	Horizon      time.Duration        `yaml:"horizon"`
+	MaxAttempts  int                  `yaml:"max_attempts"`
+	ClaimTimeout time.Duration        `yaml:"claim_timeout"`
+	Channels     []string             `yaml:"channels"`
+	Webhook      WebhookChannelConfig `yaml:"webhook"`
+	SMTP         SMTPConfig           `yaml:"smtp"`
+}
+
+type WebhookChannelConfig struct {
+	URL     string        `yaml:"url"`
+	Secret  string        `yaml:"secret"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type SMTPConfig struct {
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	From     string        `yaml:"from"`
+	To       []string      `yaml:"to"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	BackoffBase  time.Duration `yaml:"backoff_base"`
+	BackoffMax   time.Duration `yaml:"backoff_max"`
+	Timeout      time.Duration `yaml:"timeout"`
+	LeaseMargin  time.Duration `yaml:"lease_margin"`
+
+	// AllowedNetworks are CIDR networks webhooks may be delivered to even
+	// though they are internal, such as loopback or private ranges.
+	AllowedNetworks []string `yaml:"allowed_networks"`
+}
+
+type EventsConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// ArchiveConfig controls the job that moves subscriptions ended more than
+// AfterMonths months ago out of the subscriptions table into compressed
+// archive files, kept in Dir for the "local" backend or in a bucket of an
+// S3-compatible store for "s3".
+type ArchiveConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	AfterMonths int           `yaml:"after_months"`
+	Interval    time.Duration `yaml:"interval"`
+	BatchSize   int           `yaml:"batch_size"`
+	Backend     string        `yaml:"backend"`
+	Dir         string        `yaml:"dir"`
+	S3          S3Config      `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+// ValidationConfig bounds the values accepted for a subscription.
+type ValidationConfig struct {
+	MinPrice             int `yaml:"min_price"`
+	MaxPrice             int `yaml:"max_price"`
+	ServiceNameMaxLength int `yaml:"service_name_max_length"`
+	MinStartYear         int `yaml:"min_start_year"`
+	MaxStartMonthsAhead  int `yaml:"max_start_months_ahead"`
+}
+
+// CacheConfig selects where computed totals are cached: "memory" for an
+// in-process LRU, "redis" for a Redis-compatible server shared by all
+// instances, or "none".
+type CacheConfig struct {
+	Backend string        `yaml:"backend"`
+	Size    int           `yaml:"size"`
+	TTL     time.Duration `yaml:"ttl"`
+	Redis   RedisConfig   `yaml:"redis"`
+}
+
+type RedisConfig struct {
+	Addr      string `yaml:"addr"`
+	Password  string `yaml:"password"`
+	DB        int    `yaml:"db"`
+	KeyPrefix string `yaml:"key_prefix"`
+}
+
+// AdminConfig guards the administrative endpoints, such as the erasure of a
+// user and the restore of archives. They are refused until Token is set and
+// then require it in the X-Admin-Token header.
+type AdminConfig struct {
+	Token string `yaml:"token"`
+}
+
+// TenancyConfig controls how requests are attributed to tenants. When
+// disabled, everything belongs to the default tenant. RowLevelSecurity makes
+// every statement declare its tenant to Postgres, so that the row-level
+// security policies apply; the service must then connect as a role that is
+// not a superuser. The background workers that act for every tenant connect
+// through WorkerDSN instead, as a role with BYPASSRLS.
+type TenancyConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Header           string `yaml:"header"`
+	TokenSecret      string `yaml:"token_secret"`
+	TokenClaim       string `yaml:"token_claim"`
+	RowLevelSecurity bool   `yaml:"row_level_security"`
+	WorkerDSN        string `yaml:"worker_dsn"`
+}
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+			ShutdownDelay:   5 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Port:    9090,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Database: DatabaseConfig{
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 "postgres",
+			Password:             "postgres",
+			Name:                 "subscriptions",
+			SSLMode:              "disable",
+			MaxOpenConns:         25,
+			MaxIdleConns:         25,
+			ConnMaxLifetime:      5 * time.Minute,
+			AutoMigrate:          true,
+			ReadYourWritesWindow: 5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond: 50,
+			Burst:             100,
+		},
+		Features: map[string]bool{FeatureServiceSuggestions: true},
+		Reload: ReloadConfig{
+			WatchInterval: 10 * time.Second,
+		},
+		Reminders: RemindersConfig{
+			Interval:     time.Hour,
+			Horizon:      72 * time.Hour,
+			MaxAttempts:  5,
+			ClaimTimeout: 10 * time.Minute,
+			Channels:     []string{"log"},
+			Webhook: WebhookChannelConfig{
+				Timeout: 10 * time.Second,
+			},
+			SMTP: SMTPConfig{
+				Port:    587,
+				Timeout: 30 * time.Second,
+			},
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			PollInterval: 2 * time.Second,
+			BatchSize:    100,
+			MaxAttempts:  8,
+			BackoffBase:  10 * time.Second,
+			BackoffMax:   time.Hour,
+			Timeout:      10 * time.Second,
+			LeaseMargin:  30 * time.Second,
+		},
+		Events: EventsConfig{
+			PollInterval: time.Second,
+		},
+		Validation: ValidationConfig{
+			MinPrice:             1,
+			MaxPrice:             1000000,
+			ServiceNameMaxLength: 100,
+			MinStartYear:         2000,
+			MaxStartMonthsAhead:  12,
+		},
+		Cache: CacheConfig{
+			Backend: "memory",
+			Size:    10000,
+			TTL:     5 * time.Minute,
+			Redis: RedisConfig{
+				Addr:      "localhost:6379",
+				KeyPrefix: "subscription-service:",
+			},
+		},
+		Archive: ArchiveConfig{
+			AfterMonths: 12,
+			Interval:    24 * time.Hour,
+			BatchSize:   1000,
+			Backend:     "local",
+			Dir:         "archive",
+			S3:          S3Config{UseSSL: true},
+		},
+		Tenancy: TenancyConfig{
+			Header:     "X-Tenant-ID",
+			TokenClaim: "tenant_id",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file at path, an
+// optional .env file and environment variables, in that order of precedence.
+// A missing file is only tolerated for the default path.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			if !(errors.Is(err, os.ErrNotExist) && path == DefaultPath) {
+				return nil, err
+			}
+		}
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "subscriptions"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		LogLevel:   getEnv("LOG_LEVEL", "info"),
-	}, nil
+	var problems []string
+	cfg.applyEnv(&problems)
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(problems *[]string) {
+	setInt(&c.Server.Port, "SERVER_PORT", problems)
+	setDuration(&c.Server.ReadTimeout, "SERVER_READ_TIMEOUT", problems)
+	setDuration(&c.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT", problems)
+	setDuration(&c.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT", problems)
+	setDuration(&c.Server.ShutdownTimeout, "SERVER_SHUTDOWN_TIMEOUT", problems)
+	setDuration(&c.Server.ShutdownDelay, "SERVER_SHUTDOWN_DELAY", problems)
+
+	setBool(&c.GRPC.Enabled, "GRPC_ENABLED", problems)
+	setInt(&c.GRPC.Port, "GRPC_PORT", problems)
+
+	setBool(&c.GraphQL.Enabled, "GRAPHQL_ENABLED", problems)
+	setInt(&c.GraphQL.MaxDepth, "GRAPHQL_MAX_DEPTH", problems)
+	setInt(&c.GraphQL.MaxComplexity, "GRAPHQL_MAX_COMPLEXITY", problems)
+
+	setString(&c.Database.Host, "DB_HOST")
+	setInt(&c.Database.Port, "DB_PORT", problems)
+	setString(&c.Database.User, "DB_USER")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.Name, "DB_NAME")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+	setInt(&c.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS", problems)
+	setInt(&c.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS", problems)
+	setDuration(&c.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME", problems)
+	setBool(&c.Database.AutoMigrate, "DB_AUTO_MIGRATE", problems)
+	setList(&c.Database.Replicas, "DB_REPLICAS")
+	setDuration(&c.Database.ReadYourWritesWindow, "DB_READ_YOUR_WRITES_WINDOW", problems)
+
+	setString(&c.Log.Level, "LOG_LEVEL")
+	setString(&c.Log.Format, "LOG_FORMAT")
+
+	setString(&c.Tracing.OTLPEndpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	setString(&c.Tracing.File, "TRACE_FILE")
+
+	setBool(&c.RateLimit.Enabled, "RATE_LIMIT_ENABLED", problems)
+	setFloat(&c.RateLimit.RequestsPerSecond, "RATE_LIMIT_RPS", problems)
+	setInt(&c.RateLimit.Burst, "RATE_LIMIT_BURST", problems)
+	setList(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	setDuration(&c.Reload.WatchInterval, "CONFIG_WATCH_INTERVAL", problems)
+
+	setBool(&c.Reminders.Enabled, "REMINDERS_ENABLED", problems)
+	setDuration(&c.Reminders.Interval, "REMINDERS_INTERVAL", problems)
+	setDuration(&c.Reminders.Horizon, "REMINDERS_HORIZON", problems)
+	setList(&c.Reminders.Channels, "REMINDERS_CHANNELS")
+	setString(&c.Reminders.Webhook.URL, "REMINDERS_WEBHOOK_URL")
+	setString(&c.Reminders.Webhook.Secret, "REMINDERS_WEBHOOK_SECRET")
+	setString(&c.Reminders.SMTP.Host, "SMTP_HOST")
+	setInt(&c.Reminders.SMTP.Port, "SMTP_PORT", problems)
+	setString(&c.Reminders.SMTP.Username, "SMTP_USERNAME")
+	setString(&c.Reminders.SMTP.Password, "SMTP_PASSWORD")
+	setString(&c.Reminders.SMTP.From, "SMTP_FROM")
+	setList(&c.Reminders.SMTP.To, "SMTP_TO")
+	setDuration(&c.Reminders.SMTP.Timeout, "SMTP_TIMEOUT", problems)
+
+	setBool(&c.Webhooks.Enabled, "WEBHOOKS_ENABLED", problems)
+	setDuration(&c.Webhooks.PollInterval, "WEBHOOKS_POLL_INTERVAL", problems)
+	setInt(&c.Webhooks.MaxAttempts, "WEBHOOKS_MAX_ATTEMPTS", problems)
+	setList(&c.Webhooks.AllowedNetworks, "WEBHOOKS_ALLOWED_NETWORKS")
+
+	setInt(&c.Validation.MinPrice, "VALIDATION_MIN_PRICE", problems)
+	setInt(&c.Validation.MaxPrice, "VALIDATION_MAX_PRICE", problems)
+	setInt(&c.Validation.ServiceNameMaxLength, "VALIDATION_SERVICE_NAME_MAX_LENGTH", problems)
+	setInt(&c.Validation.MinStartYear, "VALIDATION_MIN_START_YEAR", problems)
+	setInt(&c.Validation.MaxStartMonthsAhead, "VALIDATION_MAX_START_MONTHS_AHEAD", problems)
+
+	setString(&c.Cache.Backend, "CACHE_BACKEND")
+	setInt(&c.Cache.Size, "CACHE_SIZE", problems)
+	setDuration(&c.Cache.TTL, "CACHE_TTL", problems)
+	setString(&c.Cache.Redis.Addr, "CACHE_REDIS_ADDR")
+	setString(&c.Cache.Redis.Password, "CACHE_REDIS_PASSWORD")
+	setInt(&c.Cache.Redis.DB, "CACHE_REDIS_DB", problems)
+
+	setBool(&c.Archive.Enabled, "ARCHIVE_ENABLED", problems)
+	setInt(&c.Archive.AfterMonths, "ARCHIVE_AFTER_MONTHS", problems)
+	setDuration(&c.Archive.Interval, "ARCHIVE_INTERVAL", problems)
+	setInt(&c.Archive.BatchSize, "ARCHIVE_BATCH_SIZE", problems)
+	setString(&c.Archive.Backend, "ARCHIVE_BACKEND")
+	setString(&c.Archive.Dir, "ARCHIVE_DIR")
+	setString(&c.Archive.S3.Endpoint, "ARCHIVE_S3_ENDPOINT")
+	setString(&c.Archive.S3.Region, "ARCHIVE_S3_REGION")
+	setString(&c.Archive.S3.Bucket, "ARCHIVE_S3_BUCKET")
+	setString(&c.Archive.S3.Prefix, "ARCHIVE_S3_PREFIX")
+	setString(&c.Archive.S3.AccessKey, "ARCHIVE_S3_ACCESS_KEY")
+	setString(&c.Archive.S3.SecretKey, "ARCHIVE_S3_SECRET_KEY")
+	setBool(&c.Archive.S3.UseSSL, "ARCHIVE_S3_USE_SSL", problems)
+
+	setString(&c.Admin.Token, "ADMIN_TOKEN")
+
+	setBool(&c.Tenancy.Enabled, "TENANCY_ENABLED", problems)
+	setString(&c.Tenancy.Header, "TENANCY_HEADER")
+	setString(&c.Tenancy.TokenSecret, "TENANCY_TOKEN_SECRET")
+	setString(&c.Tenancy.TokenClaim, "TENANCY_TOKEN_CLAIM")
+	setBool(&c.Tenancy.RowLevelSecurity, "TENANCY_ROW_LEVEL_SECURITY", problems)
+	setString(&c.Tenancy.WorkerDSN, "TENANCY_WORKER_DSN")
 }
 
-func getEnv(key, defaultValue string) string {
+func (c *Config) validate() []string {
+	var problems []string
+
+	checkPort := func(name string, port int) {
+		if port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s must be between 1 and 65535, got %d", name, port))
+		}
+	}
+	checkPositive := func(name string, d time.Duration) {
+		if d <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive, got %s", name, d))
+		}
+	}
+
+	checkPort("server.port", c.Server.Port)
+	checkPositive("server.read_timeout", c.Server.ReadTimeout)
+	checkPositive("server.write_timeout", c.Server.WriteTimeout)
+	checkPositive("server.idle_timeout", c.Server.IdleTimeout)
+	checkPositive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.ShutdownDelay < 0 {
+		problems = append(problems, fmt.Sprintf("server.shutdown_delay must not be negative, got %s", c.Server.ShutdownDelay))
+	}
+	if c.GRPC.Enabled {
+		checkPort("grpc.port", c.GRPC.Port)
+	}
+	if c.GraphQL.MaxDepth < 0 {
+		problems = append(problems, fmt.Sprintf("graphql.max_depth must not be negative, got %d", c.GraphQL.MaxDepth))
+	}
+	if c.GraphQL.MaxComplexity < 0 {
+		problems = append(problems, fmt.Sprintf("graphql.max_complexity must not be negative, got %d", c.GraphQL.MaxComplexity))
+	}
+
+	if c.Database.Host == "" {
+		problems = append(problems, "database.host is required")
+	}
+	checkPort("database.port", c.Database.Port)
+	if c.Database.User == "" {
+		problems = append(problems, "database.user is required")
+	}
+	if c.Database.Name == "" {
+		problems = append(problems, "database.name is required")
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problems = append(problems, fmt.Sprintf("database.sslmode %q is not supported", c.Database.SSLMode))
+	}
+	if c.Database.MaxOpenConns < 1 {
+		problems = append(problems, fmt.Sprintf("database.max_open_conns must be at least 1, got %d", c.Database.MaxOpenConns))
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, fmt.Sprintf("database.max_idle_conns must be between 0 and max_open_conns, got %d", c.Database.MaxIdleConns))
+	}
+	checkPositive("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	if c.Database.ReadYourWritesWindow < 0 {
+		problems = append(problems, fmt.Sprintf("database.read_your_writes_window must not be negative, got %s", c.Database.ReadYourWritesWindow))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level %q must be one of debug, info, warn, error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		problems = append(problems, fmt.Sprintf("log.format %q must be text or json", c.Log.Format))
+	}
+
+	if c.RateLimit.RequestsPerSecond <= 0 {
+		problems = append(problems, fmt.Sprintf("rate_limit.requests_per_second must be positive, got %g", c.RateLimit.RequestsPerSecond))
+	}
+	if c.RateLimit.Burst < 1 {
+		problems = append(problems, fmt.Sprintf("rate_limit.burst must be at least 1, got %d", c.RateLimit.Burst))
+	}
+	if c.Reload.WatchInterval < 0 {
+		problems = append(problems, fmt.Sprintf("reload.watch_interval must not be negative, got %s", c.Reload.WatchInterval))
+	}
+
+	checkPositive("events.poll_interval", c.Events.PollInterval)
+
+	if c.Reminders.Enabled {
+		problems = append(problems, c.Reminders.validate()...)
+	}
+	for _, cidr := range c.Webhooks.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			problems = append(problems, fmt.Sprintf("webhooks.allowed_networks: invalid network %q", cidr))
+		}
+	}
+	if c.Webhooks.Enabled {
+		problems = append(problems, c.Webhooks.validate()...)
+	}
+	problems = append(problems, c.Archive.validate()...)
+	problems = append(problems, c.Validation.validate()...)
+	problems = append(problems, c.Cache.validate()...)
+	if c.Tenancy.Enabled && c.Tenancy.Header == "" && c.Tenancy.TokenSecret == "" {
+		problems = append(problems, "tenancy.header or tenancy.token_secret is required when tenancy is enabled")
+	}
+	if c.Tenancy.TokenSecret != "" && c.Tenancy.TokenClaim == "" {
+		problems = append(problems, "tenancy.token_claim is required with a token secret")
+	}
+	if c.Tenancy.RowLevelSecurity && c.Tenancy.WorkerDSN == "" {
+		problems = append(problems, "tenancy.worker_dsn is required with row-level security")
+	}
+
+	return problems
+}
+
+func (c *CacheConfig) validate() []string {
+	var problems []string
+
+	switch c.Backend {
+	case "none":
+		return nil
+	case "memory":
+		if c.Size < 1 {
+			problems = append(problems, fmt.Sprintf("cache.size must be at least 1, got %d", c.Size))
+		}
+	case "redis":
+		if c.Redis.Addr == "" {
+			problems = append(problems, "cache.redis.addr is required for the redis backend")
+		}
+		if c.Redis.DB < 0 {
+			problems = append(problems, fmt.Sprintf("cache.redis.db must not be negative, got %d", c.Redis.DB))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("cache.backend %q must be memory, redis or none", c.Backend))
+	}
+	if c.TTL <= 0 {
+		problems = append(problems, fmt.Sprintf("cache.ttl must be positive, got %s", c.TTL))
+	}
+
+	return problems
+}
+
+func (c *ArchiveConfig) validate() []string {
+	var problems []string
+
+	switch c.Backend {
+	case "local":
+		if c.Dir == "" {
+			problems = append(problems, "archive.dir is required for the local backend")
+		}
+	case "s3":
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			problems = append(problems, "archive.s3 endpoint and bucket are required for the s3 backend")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("archive.backend %q must be local or s3", c.Backend))
+	}
+	if !c.Enabled {
+		return problems
+	}
+
+	if c.AfterMonths < 1 {
+		problems = append(problems, fmt.Sprintf("archive.after_months must be at least 1, got %d", c.AfterMonths))
+	}
+	if c.Interval <= 0 {
+		problems = append(problems, fmt.Sprintf("archive.interval must be positive, got %s", c.Interval))
+	}
+	if c.BatchSize < 1 {
+		problems = append(problems, fmt.Sprintf("archive.batch_size must be at least 1, got %d", c.BatchSize))
+	}
+
+	return problems
+}
+
+func (c *RemindersConfig) validate() []string {
+	var problems []string
+
+	if c.Interval <= 0 {
+		problems = append(problems, fmt.Sprintf("reminders.interval must be positive, got %s", c.Interval))
+	}
+	if c.Horizon <= 0 {
+		problems = append(problems, fmt.Sprintf("reminders.horizon must be positive, got %s", c.Horizon))
+	}
+	if c.MaxAttempts < 1 {
+		problems = append(problems, fmt.Sprintf("reminders.max_attempts must be at least 1, got %d", c.MaxAttempts))
+	}
+	if c.ClaimTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("reminders.claim_timeout must be positive, got %s", c.ClaimTimeout))
+	}
+	if len(c.Channels) == 0 {
+		problems = append(problems, "reminders.channels must list at least one channel")
+	}
+
+	for _, channel := range c.Channels {
+		switch channel {
+		case "log":
+		case "webhook":
+			if c.Webhook.URL == "" {
+				problems = append(problems, "reminders.webhook.url is required for the webhook channel")
+			}
+			if c.Webhook.Timeout <= 0 {
+				problems = append(problems, fmt.Sprintf("reminders.webhook.timeout must be positive, got %s", c.Webhook.Timeout))
+			}
+		case "smtp":
+			if c.SMTP.Host == "" || c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+				problems = append(problems, "reminders.smtp host, from and to are required for the smtp channel")
+			}
+			if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+				problems = append(problems, fmt.Sprintf("reminders.smtp.port must be between 1 and 65535, got %d", c.SMTP.Port))
+			}
+			if c.SMTP.Timeout <= 0 {
+				problems = append(problems, fmt.Sprintf("reminders.smtp.timeout must be positive, got %s", c.SMTP.Timeout))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("reminders.channels: unknown channel %q", channel))
+		}
+	}
+
+	return problems
+}
+
+func (c *WebhooksConfig) validate() []string {
+	var problems []string
+
+	for name, d := range map[string]time.Duration{
+		"webhooks.poll_interval": c.PollInterval,
+		"webhooks.backoff_base":  c.BackoffBase,
+		"webhooks.backoff_max":   c.BackoffMax,
+		"webhooks.timeout":       c.Timeout,
+	} {
+		if d <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive, got %s", name, d))
+		}
+	}
+	if c.LeaseMargin < 0 {
+		problems = append(problems, fmt.Sprintf("webhooks.lease_margin must not be negative, got %s", c.LeaseMargin))
+	}
+	if c.BackoffMax < c.BackoffBase {
+		problems = append(problems, "webhooks.backoff_max must not be less than backoff_base")
+	}
+	if c.BatchSize < 1 {
+		problems = append(problems, fmt.Sprintf("webhooks.batch_size must be at least 1, got %d", c.BatchSize))
+	}
+	if c.MaxAttempts < 1 {
+		problems = append(problems, fmt.Sprintf("webhooks.max_attempts must be at least 1, got %d", c.MaxAttempts))
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// FeatureEnabled reports whether the named feature toggle is switched on.
+// Toggles missing from the defaults and the file are off.
+func (c *Config) FeatureEnabled(name string) bool {
+	return c.Features[name]
+}
+
+func setString(dst *string, key string) {
 	if value := os.Getenv(key); value != "" {
-		return value
+		*dst = value
+	}
+}
+
+func setInt(dst *int, key string, problems *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func setFloat(dst *float64, key string, problems *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s must be a number, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func setBool(dst *bool, key string, problems *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s must be a boolean, got %q", key, value))
+		return
 	}
-	return defaultValue
+	*dst = parsed
+}
+
+func setList(dst *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func setDuration(dst *time.Duration, key string, problems *[]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s must be a duration, got %q", key, value))
+		return
+	}
+	*dst = parsed
 }
 
 func (c *Config) GetDBConnString() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Database.Host, c.Database.Port, c.Database.User, c.Database.Password, c.Database.Name, c.Database.SSLMode)
+}
+
+func (c *ValidationConfig) validate() []string {
+	var problems []string
+
+	if c.MinPrice < 0 {
+		problems = append(problems, fmt.Sprintf("validation.min_price must not be negative, got %d", c.MinPrice))
+	}
+	if c.MaxPrice < c.MinPrice {
+		problems = append(problems, fmt.Sprintf("validation.max_price must be at least min_price, got %d", c.MaxPrice))
+	}
+	if c.ServiceNameMaxLength < 1 || c.ServiceNameMaxLength > 255 {
+		problems = append(problems, fmt.Sprintf("validation.service_name_max_length must be between 1 and 255, got %d", c.ServiceNameMaxLength))
+	}
+	if c.MinStartYear < 1900 || c.MinStartYear > 9999 {
+		problems = append(problems, fmt.Sprintf("validation.min_start_year must be between 1900 and 9999, got %d", c.MinStartYear))
+	}
+	if c.MaxStartMonthsAhead < 0 {
+		problems = append(problems, fmt.Sprintf("validation.max_start_months_ahead must not be negative, got %d", c.MaxStartMonthsAhead))
+	}
+
+	return problems
 }
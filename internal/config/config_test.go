@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayersFileAndEnv(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 9090
+  read_timeout: 5s
+database:
+  host: db.internal
+log:
+  level: debug
+`)
+	t.Setenv("DB_HOST", "override.internal")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", cfg.Server.Port)
+	}
+	if cfg.Server.ReadTimeout != 5*time.Second {
+		t.Errorf("Expected read timeout 5s, got %s", cfg.Server.ReadTimeout)
+	}
+	if cfg.Server.WriteTimeout != 15*time.Second {
+		t.Errorf("Expected default write timeout 15s, got %s", cfg.Server.WriteTimeout)
+	}
+	if cfg.Database.Host != "override.internal" {
+		t.Errorf("Expected env override for database host, got %s", cfg.Database.Host)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("Expected log level debug, got %s", cfg.Log.Level)
+	}
+}
+
+func TestLoadAggregatesValidationErrors(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 70000
+  shutdown_delay: -1s
+log:
+  level: verbose
+`)
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+
+	_, err := Load(path)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("Expected 4 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+}
+
+func TestStoreReloadKeepsPreviousOnError(t *testing.T) {
+	path := writeConfig(t, `
+log:
+  level: info
+rate_limit:
+  burst: 10
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	store := NewStore(path, cfg)
+
+	var notified *Config
+	store.OnReload(func(c *Config) { notified = c })
+
+	if err := os.WriteFile(path, []byte("log:\n  level: debug\nserver:\n  port: 9999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ignored, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if store.Get().Log.Level != "debug" || notified == nil {
+		t.Error("Expected reloaded log level to be applied and listeners notified")
+	}
+	if store.Get().Server.Port != 8080 {
+		t.Errorf("Expected server port to keep startup value, got %d", store.Get().Server.Port)
+	}
+	if len(ignored) != 1 || ignored[0] != "server" {
+		t.Errorf("Expected server to be reported as requiring restart, got %v", ignored)
+	}
+
+	if err := os.WriteFile(path, []byte("log:\n  level: loud\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reload(); err == nil {
+		t.Error("Expected reload error for invalid config")
+	}
+	if store.Get().Log.Level != "debug" {
+		t.Errorf("Expected previous config to stay in effect, got level %s", store.Get().Log.Level)
+	}
+}
+
+func TestStoreReloadSwitchesFeatures(t *testing.T) {
+	path := writeConfig(t, "features: {}\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	store := NewStore(path, cfg)
+	if !store.Get().FeatureEnabled(FeatureServiceSuggestions) {
+		t.Error("Expected service suggestions to be enabled by default")
+	}
+
+	if err := os.WriteFile(path, []byte("features:\n  service_suggestions: false\n  beta: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reload(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if store.Get().FeatureEnabled(FeatureServiceSuggestions) {
+		t.Error("Expected service suggestions to be disabled after reload")
+	}
+	if !store.Get().FeatureEnabled("beta") {
+		t.Error("Expected beta to be enabled after reload")
+	}
+	if cfg.FeatureEnabled("beta") {
+		t.Error("Expected reload to leave the previous config untouched")
+	}
+}
+
+func TestStoreReloadReportsWorkerSettings(t *testing.T) {
+	path := writeConfig(t, "reminders:\n  horizon: 72h\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	store := NewStore(path, cfg)
+
+	content := "reminders:\n  horizon: 24h\nwebhooks:\n  allowed_networks: [10.0.0.0/8]\nevents:\n  poll_interval: 5s\nreload:\n  watch_interval: 1m\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ignored, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+
+	want := []string{"reminders", "webhooks", "events", "reload"}
+	if len(ignored) != len(want) {
+		t.Fatalf("Expected %v to be reported as requiring restart, got %v", want, ignored)
+	}
+	for i := range want {
+		if ignored[i] != want[i] {
+			t.Errorf("Expected %v to be reported as requiring restart, got %v", want, ignored)
+			break
+		}
+	}
+	if store.Get().Reminders.Horizon != 72*time.Hour {
+		t.Errorf("Expected reminders horizon to keep startup value, got %s", store.Get().Reminders.Horizon)
+	}
+}
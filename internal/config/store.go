@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the live configuration. Only runtime settings (log level,
+// rate limits, CORS origins, the admin token, feature toggles and GraphQL
+// limits) are replaced on reload; everything else keeps its startup value
+// until the process restarts.
+type Store struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu        sync.Mutex
+	listeners []func(*Config)
+	modTime   time.Time
+}
+
+func NewStore(path string, cfg *Config) *Store {
+	s := &Store{path: path}
+	s.current.Store(cfg)
+	s.modTime = fileModTime(path)
+	return s
+}
+
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after every
+// successful reload.
+func (s *Store) OnReload(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Reload re-reads the configuration. On failure the previous configuration
+// stays in effect and the error is returned. The second result lists the
+// settings that changed but require a restart to take effect.
+func (s *Store) Reload() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.modTime = fileModTime(s.path)
+
+	loaded, err := Load(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := s.current.Load()
+	next := *prev
+	next.Log.Level = loaded.Log.Level
+	next.RateLimit = loaded.RateLimit
+	next.CORS = loaded.CORS
+	next.Admin = loaded.Admin
+	next.Features = loaded.Features
+	next.GraphQL.MaxDepth = loaded.GraphQL.MaxDepth
+	next.GraphQL.MaxComplexity = loaded.GraphQL.MaxComplexity
+
+	var ignored []string
+	if !reflect.DeepEqual(prev.Server, loaded.Server) {
+		ignored = append(ignored, "server")
+	}
+	if prev.GRPC != loaded.GRPC {
+		ignored = append(ignored, "grpc")
+	}
+	if prev.GraphQL.Enabled != loaded.GraphQL.Enabled {
+		ignored = append(ignored, "graphql.enabled")
+	}
+	if prev.Validation != loaded.Validation {
+		ignored = append(ignored, "validation")
+	}
+	if prev.Cache != loaded.Cache {
+		ignored = append(ignored, "cache")
+	}
+	if prev.Archive != loaded.Archive {
+		ignored = append(ignored, "archive")
+	}
+	if prev.Tenancy != loaded.Tenancy {
+		ignored = append(ignored, "tenancy")
+	}
+	if !reflect.DeepEqual(prev.Reminders, loaded.Reminders) {
+		ignored = append(ignored, "reminders")
+	}
+	if !reflect.DeepEqual(prev.Webhooks, loaded.Webhooks) {
+		ignored = append(ignored, "webhooks")
+	}
+	if prev.Events != loaded.Events {
+		ignored = append(ignored, "events")
+	}
+	if prev.Reload != loaded.Reload {
+		ignored = append(ignored, "reload")
+	}
+	if !reflect.DeepEqual(prev.Database, loaded.Database) {
+		ignored = append(ignored, "database")
+	}
+	if prev.Log.Format != loaded.Log.Format {
+		ignored = append(ignored, "log.format")
+	}
+	if prev.Tracing != loaded.Tracing {
+		ignored = append(ignored, "tracing")
+	}
+
+	s.current.Store(&next)
+	for _, fn := range s.listeners {
+		fn(&next)
+	}
+
+	return ignored, nil
+}
+
+// Changed reports whether the config file was modified since the last load.
+func (s *Store) Changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !fileModTime(s.path).Equal(s.modTime)
+}
+
+// Watch polls the config file and calls onChange whenever it was modified,
+// until ctx is cancelled.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, onChange func()) {
+	if interval <= 0 || s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.Changed() {
+				onChange()
+			}
+		}
+	}
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"subscription-service/internal/health"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+const (
+	subscriberBuffer = 64
+	pollBatchSize    = 500
+)
+
+// Subscriber receives events matching its filter. C is closed when the
+// subscriber falls too far behind or the broker stops; clients are expected
+// to reconnect and resume from the last event id they saw.
+type Subscriber struct {
+	C        <-chan models.Event
+	ch       chan models.Event
+	tenantID string
+	userID   *uuid.UUID
+}
+
+// Broker polls the outbox table and fans new events out to in-process
+// subscribers, so that streaming clients do not each query the database.
+// Events are published in commit order: those of a transaction only once no
+// older transaction is running, so that a late commit is never passed over.
+type Broker struct {
+	repo     *repository.EventRepository
+	interval time.Duration
+	logger   *logger.Logger
+	worker   *health.Worker
+
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+	last        models.Event
+}
+
+func NewBroker(repo *repository.EventRepository, interval time.Duration, logger *logger.Logger) *Broker {
+	return &Broker{
+		repo:        repo,
+		interval:    interval,
+		logger:      logger,
+		worker:      health.NewWorker(3*interval + 5*time.Second),
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+func (b *Broker) Worker() *health.Worker {
+	return b.worker
+}
+
+// Subscribe registers a subscriber to the events of a tenant, optionally
+// restricted to one user. After Close, the subscriber is closed at once.
+func (b *Broker) Subscribe(tenantID string, userID *uuid.UUID) *Subscriber {
+	ch := make(chan models.Event, subscriberBuffer)
+	sub := &Subscriber{C: ch, ch: ch, tenantID: tenantID, userID: userID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Backlog returns the committed events of the tenant of ctx that follow
+// afterID for a resuming client, in the order the broker publishes them.
+func (b *Broker) Backlog(ctx context.Context, afterID int64, userID *uuid.UUID, limit int) ([]models.Event, error) {
+	horizon, err := b.repo.Horizon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.repo.ListAfter(ctx, afterID, horizon, tenant.FromContext(ctx), userID, limit)
+}
+
+// Follows reports whether e comes after last in the order events are
+// published.
+func Follows(e, last models.Event) bool {
+	if e.TxID != last.TxID {
+		return e.TxID > last.TxID
+	}
+	return e.ID > last.ID
+}
+
+func (b *Broker) Run(ctx context.Context) {
+	defer b.worker.Stop()
+	defer b.Close()
+
+	for {
+		horizon, err := b.repo.Horizon(ctx)
+		if err == nil {
+			b.last = models.Event{TxID: horizon}
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		b.logger.Error("failed to read event horizon", "error", err)
+		time.Sleep(b.interval)
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		b.worker.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := b.poll(ctx); err != nil && ctx.Err() == nil {
+			b.logger.Error("failed to poll events", "error", err)
+		}
+	}
+}
+
+func (b *Broker) poll(ctx context.Context) error {
+	horizon, err := b.repo.Horizon(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		events, err := b.repo.ListCommitted(ctx, b.last, horizon, "", nil, pollBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			b.publish(e)
+			b.last = e
+		}
+		if len(events) < pollBatchSize {
+			return nil
+		}
+	}
+}
+
+func (b *Broker) publish(e models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.tenantID != e.TenantID || (sub.userID != nil && *sub.userID != e.UserID) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close closes every subscriber and the ones subscribing later, so that
+// streaming clients end their requests and a server shutdown does not wait
+// for them.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
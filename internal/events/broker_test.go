@@ -0,0 +1,94 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestBrokerPublishFiltersByUser(t *testing.T) {
+	broker := NewBroker(nil, time.Second, nil)
+
+	userID := uuid.New()
+	all := broker.Subscribe("acme", nil)
+	own := broker.Subscribe("acme", &userID)
+
+	broker.publish(models.Event{ID: 1, UserID: uuid.New(), TenantID: "acme"})
+	broker.publish(models.Event{ID: 2, UserID: userID, TenantID: "acme"})
+
+	if got := len(all.C); got != 2 {
+		t.Errorf("Expected 2 events for unfiltered subscriber, got %d", got)
+	}
+	if got := len(own.C); got != 1 {
+		t.Fatalf("Expected 1 event for user subscriber, got %d", got)
+	}
+	if e := <-own.C; e.ID != 2 {
+		t.Errorf("Expected event 2, got %d", e.ID)
+	}
+}
+
+func TestBrokerPublishFiltersByTenant(t *testing.T) {
+	broker := NewBroker(nil, time.Second, nil)
+	userID := uuid.New()
+	sub := broker.Subscribe("acme", nil)
+
+	broker.publish(models.Event{ID: 1, UserID: userID, TenantID: "globex"})
+	broker.publish(models.Event{ID: 2, UserID: userID, TenantID: "acme"})
+
+	if got := len(sub.C); got != 1 {
+		t.Fatalf("Expected 1 event for tenant subscriber, got %d", got)
+	}
+	if e := <-sub.C; e.ID != 2 {
+		t.Errorf("Expected event 2, got %d", e.ID)
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(nil, time.Second, nil)
+	sub := broker.Subscribe("acme", nil)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		broker.publish(models.Event{ID: int64(i + 1), TenantID: "acme"})
+	}
+
+	for range sub.C {
+	}
+	broker.Unsubscribe(sub)
+}
+
+func TestBrokerCloseEndsSubscribers(t *testing.T) {
+	broker := NewBroker(nil, time.Second, nil)
+
+	before := broker.Subscribe("acme", nil)
+	broker.Close()
+	after := broker.Subscribe("acme", nil)
+
+	if _, ok := <-before.C; ok {
+		t.Error("Expected subscriber to be closed by Close")
+	}
+	if _, ok := <-after.C; ok {
+		t.Error("Expected subscriber after Close to be closed")
+	}
+	broker.Unsubscribe(after)
+	broker.Close()
+}
+
+func TestFollowsOrdersByTransactionFirst(t *testing.T) {
+	last := models.Event{ID: 10, TxID: 100}
+
+	if Follows(models.Event{ID: 9, TxID: 100}, last) {
+		t.Error("Expected an earlier id of the same transaction not to follow")
+	}
+	if !Follows(models.Event{ID: 11, TxID: 100}, last) {
+		t.Error("Expected a later id of the same transaction to follow")
+	}
+	if !Follows(models.Event{ID: 5, TxID: 101}, last) {
+		t.Error("Expected an event committed by a later transaction to follow despite its lower id")
+	}
+	if Follows(models.Event{ID: 12, TxID: 99}, last) {
+		t.Error("Expected an event of an older transaction not to follow")
+	}
+}
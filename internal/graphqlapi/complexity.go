@@ -0,0 +1,134 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is the number of items a list field is assumed to return
+// when the query does not say how many it asks for.
+const defaultListSize = 20
+
+// cost is the result of analyzing an operation before executing it.
+type cost struct {
+	Depth      int
+	Complexity int
+}
+
+// analyze estimates the depth and complexity of the named operation of doc.
+// Every field costs one, and the cost of the selections under a list field
+// is multiplied by the expected list size. Introspection fields are free so
+// that tooling keeps working under tight limits.
+func analyze(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (cost, bool) {
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if op == nil || op.Operation != ast.OperationTypeQuery {
+		return cost{}, false
+	}
+
+	a := &analyzer{fragments: fragments, variables: variables, visiting: make(map[string]bool)}
+	return a.selectionSet(schema.QueryType(), op.SelectionSet, 1), true
+}
+
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func (a *analyzer) selectionSet(parent *graphql.Object, set *ast.SelectionSet, depth int) cost {
+	var total cost
+	if set == nil || parent == nil {
+		return total
+	}
+
+	add := func(c cost) {
+		total.Complexity += c.Complexity
+		if c.Depth > total.Depth {
+			total.Depth = c.Depth
+		}
+	}
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			add(a.field(parent, sel, depth))
+		case *ast.InlineFragment:
+			add(a.selectionSet(parent, sel.SelectionSet, depth))
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := a.fragments[name]
+			if !ok || a.visiting[name] {
+				continue
+			}
+			a.visiting[name] = true
+			add(a.selectionSet(parent, frag.SelectionSet, depth))
+			a.visiting[name] = false
+		}
+	}
+	return total
+}
+
+func (a *analyzer) field(parent *graphql.Object, f *ast.Field, depth int) cost {
+	name := f.Name.Value
+	if len(name) >= 2 && name[:2] == "__" {
+		return cost{}
+	}
+
+	def, ok := parent.Fields()[name]
+	if !ok {
+		return cost{Depth: depth, Complexity: 1}
+	}
+
+	multiplier := 1
+	typ := def.Type
+	if nn, ok := typ.(*graphql.NonNull); ok {
+		typ = nn.OfType
+	}
+	if list, ok := typ.(*graphql.List); ok {
+		multiplier = a.listSize(f)
+		typ = list.OfType
+		if nn, ok := typ.(*graphql.NonNull); ok {
+			typ = nn.OfType
+		}
+	}
+
+	c := cost{Depth: depth, Complexity: 1}
+	if obj, ok := typ.(*graphql.Object); ok && f.SelectionSet != nil {
+		child := a.selectionSet(obj, f.SelectionSet, depth+1)
+		c.Complexity += multiplier * child.Complexity
+		if child.Depth > c.Depth {
+			c.Depth = child.Depth
+		}
+	}
+	return c
+}
+
+// listSize uses the length of an "ids" argument when the query passes one,
+// either inline or as a variable.
+func (a *analyzer) listSize(f *ast.Field) int {
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "ids" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.ListValue:
+			return max(len(v.Values), 1)
+		case *ast.Variable:
+			if ids, ok := a.variables[v.Name.Value].([]interface{}); ok {
+				return max(len(ids), 1)
+			}
+		}
+	}
+	return defaultListSize
+}
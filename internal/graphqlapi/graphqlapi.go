@@ -0,0 +1,71 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+
+	"subscription-service/internal/service"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is the body of a GraphQL-over-HTTP POST.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Limits bound the cost of a single operation. Zero disables a limit.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+type Executor struct {
+	schema  graphql.Schema
+	service *service.SubscriptionService
+}
+
+func NewExecutor(service *service.SubscriptionService) (*Executor, error) {
+	schema, err := newSchema(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graphql schema: %w", err)
+	}
+	return &Executor{schema: schema, service: service}, nil
+}
+
+// Execute rejects operations over limits before running any resolver, then
+// executes req with request-scoped loaders.
+func (e *Executor) Execute(ctx context.Context, req Request, limits Limits) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	if c, ok := analyze(e.schema, doc, req.OperationName, req.Variables); ok {
+		if limits.MaxDepth > 0 && c.Depth > limits.MaxDepth {
+			return errorResult(fmt.Sprintf("query depth %d exceeds the limit of %d", c.Depth, limits.MaxDepth))
+		}
+		if limits.MaxComplexity > 0 && c.Complexity > limits.MaxComplexity {
+			return errorResult(fmt.Sprintf("query complexity %d exceeds the limit of %d", c.Complexity, limits.MaxComplexity))
+		}
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         e.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoaders(ctx, e.service),
+	})
+}
+
+func errorResult(message string) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}}
+}
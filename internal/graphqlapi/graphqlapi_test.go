@@ -0,0 +1,135 @@
+package graphqlapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestLoaderBatchesSiblingFields(t *testing.T) {
+	calls := 0
+	ld := newLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		calls++
+		values := make(map[int]int, len(keys))
+		for _, k := range keys {
+			values[k] = k * 10
+		}
+		return values, nil
+	})
+
+	item := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"value": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return ld.load(p.Context, p.Source.(int)), nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"items": &graphql.Field{
+					Type: graphql.NewList(item),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return []int{1, 2, 3}, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ items { value } }", Context: context.Background()})
+	if result.HasErrors() {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 batch call, got %d", calls)
+	}
+
+	items := result.Data.(map[string]interface{})["items"].([]interface{})
+	if got := items[2].(map[string]interface{})["value"]; got != 30 {
+		t.Errorf("Expected value 30, got %v", got)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	schema, err := newSchema(nil)
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		variables  map[string]interface{}
+		depth      int
+		complexity int
+	}{
+		{
+			name:       "scalar",
+			query:      `{ total }`,
+			depth:      1,
+			complexity: 1,
+		},
+		{
+			name:       "list uses default size",
+			query:      `{ subscriptions { id price } }`,
+			depth:      2,
+			complexity: 1 + defaultListSize*2,
+		},
+		{
+			name:       "users sized by ids",
+			query:      `query($ids: [ID!]!) { users(ids: $ids) { id total } }`,
+			variables:  map[string]interface{}{"ids": []interface{}{"a", "b", "c"}},
+			depth:      2,
+			complexity: 1 + 3*2,
+		},
+		{
+			name:       "fragments and introspection",
+			query:      `{ __typename user(id: "x") { ...f } } fragment f on User { subscriptions { user { id } } }`,
+			depth:      4,
+			complexity: 1 + 1 + defaultListSize*2,
+		},
+	}
+
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("%s: failed to parse query: %v", tt.name, err)
+		}
+		c, ok := analyze(schema, doc, "", tt.variables)
+		if !ok {
+			t.Fatalf("%s: expected a query operation", tt.name)
+		}
+		if c.Depth != tt.depth {
+			t.Errorf("%s: expected depth %d, got %d", tt.name, tt.depth, c.Depth)
+		}
+		if c.Complexity != tt.complexity {
+			t.Errorf("%s: expected complexity %d, got %d", tt.name, tt.complexity, c.Complexity)
+		}
+	}
+}
+
+func TestExecuteRejectsComplexQueries(t *testing.T) {
+	executor, err := NewExecutor(nil)
+	if err != nil {
+		t.Fatalf("Failed to build executor: %v", err)
+	}
+
+	result := executor.Execute(context.Background(), Request{Query: `{ subscriptions { id user { subscriptions { id } } } }`}, Limits{MaxComplexity: 100})
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %v", result.Errors)
+	}
+	if result.Data != nil {
+		t.Errorf("Expected no data, got %v", result.Data)
+	}
+}
@@ -0,0 +1,64 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+)
+
+// batchFunc loads the values of several keys at once. Keys missing from the
+// result resolve to the zero value.
+type batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// loader collects the keys requested while the executor resolves one level
+// of the query and fetches them with a single batchFunc call the first time
+// any of their values is needed. Results are cached for the request.
+type loader[K comparable, V any] struct {
+	fetch batchFunc[K, V]
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	loaded  map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch batchFunc[K, V]) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, loaded: make(map[K]*batch[K, V])}
+}
+
+// load schedules key and returns a thunk in the form graphql-go resolves
+// lazily, after every sibling field had the chance to schedule its key.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (interface{}, error) {
+	l.mu.Lock()
+	b, ok := l.loaded[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[K, V]{}
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.loaded[key] = b
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			if l.pending == b {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+
+			b.values, b.err = l.fetch(ctx, b.keys)
+		})
+		if b.err != nil {
+			return nil, b.err
+		}
+		return b.values[key], nil
+	}
+}
@@ -0,0 +1,304 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// user is the source value of the User type. Users have no table of their
+// own; they are the distinct user ids of subscriptions.
+type user struct {
+	ID uuid.UUID
+}
+
+type resolvers struct {
+	service *service.SubscriptionService
+}
+
+func newSchema(svc *service.SubscriptionService) (graphql.Schema, error) {
+	r := &resolvers{service: svc}
+
+	filterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "SubscriptionFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"userId":      &graphql.InputObjectFieldConfig{Type: graphql.ID},
+			"serviceName": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"startMonth":  &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "MM-YYYY"},
+			"endMonth":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "MM-YYYY"},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "User",
+		Fields: graphql.Fields{},
+	})
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.ID.String() })},
+			"serviceName": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.ServiceName })},
+			"price":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.Price })},
+			"userId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.UserID.String() })},
+			"user":        &graphql.Field{Type: graphql.NewNonNull(userType), Resolve: subscriptionField(func(s models.Subscription) interface{} { return user{ID: s.UserID} })},
+			"startDate":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.StartDate.Format("01-2006") })},
+			"endDate": &graphql.Field{Type: graphql.String, Resolve: subscriptionField(func(s models.Subscription) interface{} {
+				if s.EndDate == nil {
+					return nil
+				}
+				return s.EndDate.Format("01-2006")
+			})},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.CreatedAt })},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: subscriptionField(func(s models.Subscription) interface{} { return s.UpdatedAt })},
+		},
+	})
+
+	userType.AddFieldConfig("id", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.ID),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(user).ID.String(), nil
+		},
+	})
+	userType.AddFieldConfig("subscriptions", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(subscriptionType))),
+		Args: graphql.FieldConfigArgument{
+			"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: r.userSubscriptions,
+	})
+	userType.AddFieldConfig("total", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Int),
+		Args: graphql.FieldConfigArgument{
+			"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+			"startMonth":  &graphql.ArgumentConfig{Type: graphql.String},
+			"endMonth":    &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: r.userTotal,
+	})
+
+	monthlyCostType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MonthlyCost",
+		Fields: graphql.Fields{
+			"month": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	monthlyBreakdownType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MonthlyBreakdown",
+		Fields: graphql.Fields{
+			"months": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(monthlyCostType)))},
+			"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	filterArgs := graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{Type: filterInput},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"subscription": &graphql.Field{
+				Type:    subscriptionType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: r.subscription,
+			},
+			"subscriptions": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(subscriptionType))),
+				Args:    filterArgs,
+				Resolve: r.subscriptions,
+			},
+			"total": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Args:    filterArgs,
+				Resolve: r.total,
+			},
+			"monthlyBreakdown": &graphql.Field{
+				Type:    graphql.NewNonNull(monthlyBreakdownType),
+				Args:    filterArgs,
+				Resolve: r.monthlyBreakdown,
+			},
+			"user": &graphql.Field{
+				Type:    userType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: r.user,
+			},
+			"users": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Args:    graphql.FieldConfigArgument{"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))}},
+				Resolve: r.users,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+func subscriptionField(get func(models.Subscription) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(models.Subscription)), nil
+	}
+}
+
+func (r *resolvers) subscription(p graphql.ResolveParams) (interface{}, error) {
+	sub, err := r.service.GetByID(p.Context, p.Args["id"].(string))
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return *sub, nil
+}
+
+func (r *resolvers) subscriptions(p graphql.ResolveParams) (interface{}, error) {
+	subs, err := r.service.List(p.Context, filterArg(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *resolvers) total(p graphql.ResolveParams) (interface{}, error) {
+	return r.service.GetTotalCost(p.Context, filterArg(p.Args))
+}
+
+func (r *resolvers) monthlyBreakdown(p graphql.ResolveParams) (interface{}, error) {
+	breakdown, err := r.service.GetMonthlyBreakdown(p.Context, filterArg(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	months := make([]interface{}, 0, len(breakdown.Months))
+	for _, m := range breakdown.Months {
+		months = append(months, map[string]interface{}{"month": m.Month, "total": m.Total})
+	}
+	return map[string]interface{}{"months": months, "total": breakdown.Total}, nil
+}
+
+func (r *resolvers) user(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, &service.ArgumentError{Message: "invalid user id format"}
+	}
+	return user{ID: id}, nil
+}
+
+func (r *resolvers) users(p graphql.ResolveParams) (interface{}, error) {
+	raw := p.Args["ids"].([]interface{})
+	users := make([]user, 0, len(raw))
+	for _, v := range raw {
+		id, err := uuid.Parse(v.(string))
+		if err != nil {
+			return nil, &service.ArgumentError{Message: "invalid user id format"}
+		}
+		users = append(users, user{ID: id})
+	}
+	return users, nil
+}
+
+func (r *resolvers) userSubscriptions(p graphql.ResolveParams) (interface{}, error) {
+	filter := models.SubscriptionFilter{ServiceName: stringArg(p.Args, "serviceName")}
+	return loadersFrom(p.Context, r.service).subscriptions(filter).load(p.Context, p.Source.(user).ID), nil
+}
+
+func (r *resolvers) userTotal(p graphql.ResolveParams) (interface{}, error) {
+	filter := models.SubscriptionFilter{
+		ServiceName: stringArg(p.Args, "serviceName"),
+		StartMonth:  stringArg(p.Args, "startMonth"),
+		EndMonth:    stringArg(p.Args, "endMonth"),
+	}
+	return loadersFrom(p.Context, r.service).totals(filter).load(p.Context, p.Source.(user).ID), nil
+}
+
+func filterArg(args map[string]interface{}) *models.SubscriptionFilter {
+	raw, _ := args["filter"].(map[string]interface{})
+	return &models.SubscriptionFilter{
+		UserID:      stringArg(raw, "userId"),
+		ServiceName: stringArg(raw, "serviceName"),
+		StartMonth:  stringArg(raw, "startMonth"),
+		EndMonth:    stringArg(raw, "endMonth"),
+	}
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+type loadersKey struct{}
+
+// loaders holds the batching loaders of one request, one per distinct set of
+// field arguments.
+type loaders struct {
+	service *service.SubscriptionService
+
+	mu                sync.Mutex
+	subscriptionsByID map[models.SubscriptionFilter]*loader[uuid.UUID, []models.Subscription]
+	totalsByID        map[models.SubscriptionFilter]*loader[uuid.UUID, int]
+}
+
+func newLoaders(svc *service.SubscriptionService) *loaders {
+	return &loaders{
+		service:           svc,
+		subscriptionsByID: make(map[models.SubscriptionFilter]*loader[uuid.UUID, []models.Subscription]),
+		totalsByID:        make(map[models.SubscriptionFilter]*loader[uuid.UUID, int]),
+	}
+}
+
+func withLoaders(ctx context.Context, svc *service.SubscriptionService) context.Context {
+	return context.WithValue(ctx, loadersKey{}, newLoaders(svc))
+}
+
+// loadersFrom returns the loaders of the request, or fresh unshared ones when
+// the schema is executed without withLoaders.
+func loadersFrom(ctx context.Context, svc *service.SubscriptionService) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(svc)
+}
+
+func (l *loaders) subscriptions(filter models.SubscriptionFilter) *loader[uuid.UUID, []models.Subscription] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ld, ok := l.subscriptionsByID[filter]
+	if !ok {
+		ld = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]models.Subscription, error) {
+			byUser, err := l.service.ListByUsers(ctx, ids, &filter)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				if byUser[id] == nil {
+					byUser[id] = []models.Subscription{}
+				}
+			}
+			return byUser, nil
+		})
+		l.subscriptionsByID[filter] = ld
+	}
+	return ld
+}
+
+func (l *loaders) totals(filter models.SubscriptionFilter) *loader[uuid.UUID, int] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ld, ok := l.totalsByID[filter]
+	if !ok {
+		ld = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+			return l.service.GetTotalCostByUsers(ctx, ids, &filter)
+		})
+		l.totalsByID[filter] = ld
+	}
+	return ld
+}
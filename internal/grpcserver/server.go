@@ -0,0 +1,293 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	subscriptionv1 "subscription-service/api/gen/subscription/v1"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const requestIDMetadataKey = "x-request-id"
+
+// Server implements the gRPC SubscriptionService on top of the same
+// service layer as the REST handlers.
+type Server struct {
+	subscriptionv1.UnimplementedSubscriptionServiceServer
+
+	service *service.SubscriptionService
+	logger  *logger.Logger
+}
+
+func New(service *service.SubscriptionService, tenants *tenant.Resolver, logger *logger.Logger) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			recoveryInterceptor(logger),
+			loggingInterceptor(logger),
+			tenantInterceptor(tenants),
+		),
+	)
+
+	subscriptionv1.RegisterSubscriptionServiceServer(srv, &Server{service: service, logger: logger})
+	reflection.Register(srv)
+
+	return srv
+}
+
+func (s *Server) CreateSubscription(ctx context.Context, req *subscriptionv1.CreateSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	sub, err := s.service.Create(ctx, &models.CreateSubscriptionRequest{
+		ServiceName: req.GetServiceName(),
+		Price:       int(req.GetPrice()),
+		UserID:      req.GetUserId(),
+		StartDate:   req.GetStartDate(),
+		EndDate:     req.GetEndDate(),
+
+		AllowOverlap: req.GetAllowOverlap(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(sub), nil
+}
+
+func (s *Server) GetSubscription(ctx context.Context, req *subscriptionv1.GetSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	sub, err := s.service.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(sub), nil
+}
+
+// UpdateSubscription changes the fields set in req and keeps the others, as
+// a merge patch does over REST; an empty end date clears it.
+func (s *Server) UpdateSubscription(ctx context.Context, req *subscriptionv1.UpdateSubscriptionRequest) (*subscriptionv1.Subscription, error) {
+	patch, err := mergePatch(req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	sub, err := s.service.Patch(ctx, req.GetId(), service.MergePatch, patch, req.GetAllowOverlap())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(sub), nil
+}
+
+// mergePatch turns the fields set in req into a JSON Merge Patch of the
+// subscription.
+func mergePatch(req *subscriptionv1.UpdateSubscriptionRequest) ([]byte, error) {
+	patch := make(map[string]interface{})
+	if req.ServiceName != nil {
+		patch["service_name"] = req.GetServiceName()
+	}
+	if req.Price != nil {
+		patch["price"] = req.GetPrice()
+	}
+	if req.GetStartDate() != "" {
+		patch["start_date"] = req.GetStartDate()
+	}
+	if req.EndDate != nil {
+		if req.GetEndDate() == "" {
+			patch["end_date"] = nil
+		} else {
+			patch["end_date"] = req.GetEndDate()
+		}
+	}
+	return json.Marshal(patch)
+}
+
+func (s *Server) DeleteSubscription(ctx context.Context, req *subscriptionv1.DeleteSubscriptionRequest) (*subscriptionv1.DeleteSubscriptionResponse, error) {
+	if err := s.service.Delete(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &subscriptionv1.DeleteSubscriptionResponse{}, nil
+}
+
+func (s *Server) ListSubscriptions(ctx context.Context, req *subscriptionv1.ListSubscriptionsRequest) (*subscriptionv1.ListSubscriptionsResponse, error) {
+	subs, err := s.service.List(ctx, toFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &subscriptionv1.ListSubscriptionsResponse{
+		Subscriptions: make([]*subscriptionv1.Subscription, 0, len(subs)),
+	}
+	for i := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, toProto(&subs[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetTotalCost(ctx context.Context, req *subscriptionv1.GetTotalCostRequest) (*subscriptionv1.GetTotalCostResponse, error) {
+	total, err := s.service.GetTotalCost(ctx, toFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &subscriptionv1.GetTotalCostResponse{Total: int64(total)}, nil
+}
+
+func (s *Server) GetMonthlyBreakdown(ctx context.Context, req *subscriptionv1.GetMonthlyBreakdownRequest) (*subscriptionv1.GetMonthlyBreakdownResponse, error) {
+	breakdown, err := s.service.GetMonthlyBreakdown(ctx, toFilter(req.GetFilter()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &subscriptionv1.GetMonthlyBreakdownResponse{
+		Months: make([]*subscriptionv1.MonthlyCost, 0, len(breakdown.Months)),
+		Total:  int64(breakdown.Total),
+	}
+	for _, m := range breakdown.Months {
+		resp.Months = append(resp.Months, &subscriptionv1.MonthlyCost{Month: m.Month, Total: int64(m.Total)})
+	}
+	return resp, nil
+}
+
+func toProto(sub *models.Subscription) *subscriptionv1.Subscription {
+	pb := &subscriptionv1.Subscription{
+		Id:          sub.ID.String(),
+		ServiceName: sub.ServiceName,
+		Price:       int64(sub.Price),
+		UserId:      sub.UserID.String(),
+		StartDate:   sub.StartDate.Format("01-2006"),
+		CreatedAt:   timestamppb.New(sub.CreatedAt),
+		UpdatedAt:   timestamppb.New(sub.UpdatedAt),
+	}
+	if sub.EndDate != nil {
+		endDate := sub.EndDate.Format("01-2006")
+		pb.EndDate = &endDate
+	}
+	return pb
+}
+
+func toFilter(f *subscriptionv1.SubscriptionFilter) *models.SubscriptionFilter {
+	return &models.SubscriptionFilter{
+		UserID:      f.GetUserId(),
+		ServiceName: f.GetServiceName(),
+		StartMonth:  f.GetStartMonth(),
+		EndMonth:    f.GetEndMonth(),
+	}
+}
+
+func toStatus(err error) error {
+	var validation *service.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		return validationStatus(validation)
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case service.IsArgumentError(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrPatchTestFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, new(*service.ConflictError)):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// validationStatus reports every field error as a BadRequest detail, with the
+// machine-readable code as the violation reason.
+func validationStatus(err *service.ValidationError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+			Reason:      f.Code,
+		})
+	}
+
+	st, detailErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if detailErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return st.Err()
+}
+
+func loggingInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		requestID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+		requestLogger := log.With("request_id", requestID)
+		resp, err := handler(logger.WithContext(ctx, requestLogger), req)
+
+		code := status.Code(err)
+		attrs := []any{"method", info.FullMethod, "code", code.String(), "latency", time.Since(start)}
+		switch code {
+		case codes.OK, codes.NotFound, codes.InvalidArgument, codes.Canceled:
+			requestLogger.Info("rpc completed", attrs...)
+		default:
+			requestLogger.Error("rpc completed", append(attrs, "error", err)...)
+		}
+		return resp, err
+	}
+}
+
+// tenantInterceptor resolves the tenant of a call from the metadata named
+// after the tenant header and the authorization metadata.
+func tenantInterceptor(resolver *tenant.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		first := func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		id, err := resolver.Resolve(first(strings.ToLower(resolver.Header())), first("authorization"))
+		if errors.Is(err, tenant.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return handler(tenant.WithID(ctx, id), req)
+	}
+}
+
+func recoveryInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("rpc panicked", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
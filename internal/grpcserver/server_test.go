@@ -0,0 +1,67 @@
+package grpcserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	subscriptionv1 "subscription-service/api/gen/subscription/v1"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{service.ErrNotFound, codes.NotFound},
+		{&service.ArgumentError{Message: "invalid id format"}, codes.InvalidArgument},
+		{&service.ConflictError{Message: "subscription overlaps an existing subscription to the same service"}, codes.AlreadyExists},
+		{errors.New("connection refused"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		if got := status.Code(toStatus(tt.err)); got != tt.code {
+			t.Errorf("Expected %s for %q, got %s", tt.code, tt.err, got)
+		}
+	}
+}
+
+func TestToProto(t *testing.T) {
+	end := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserID:      uuid.New(),
+		StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     &end,
+	}
+
+	pb := toProto(sub)
+	if pb.GetStartDate() != "07-2025" {
+		t.Errorf("Expected start date 07-2025, got %s", pb.GetStartDate())
+	}
+	if pb.GetEndDate() != "12-2025" {
+		t.Errorf("Expected end date 12-2025, got %s", pb.GetEndDate())
+	}
+	if pb.GetPrice() != 400 {
+		t.Errorf("Expected price 400, got %d", pb.GetPrice())
+	}
+}
+
+func TestMergePatchKeepsUnsetFields(t *testing.T) {
+	name, end := "Netflix", ""
+	patch, err := mergePatch(&subscriptionv1.UpdateSubscriptionRequest{Id: uuid.NewString(), ServiceName: &name, EndDate: &end})
+	if err != nil {
+		t.Fatalf("Failed to build patch: %v", err)
+	}
+	if got := string(patch); got != `{"end_date":null,"service_name":"Netflix"}` {
+		t.Errorf("Expected only the set fields with a cleared end date, got %s", got)
+	}
+}
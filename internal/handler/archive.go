@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"subscription-service/internal/archive"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ArchiveHandler struct {
+	archiver *archive.Archiver
+	logger   *logger.Logger
+}
+
+func NewArchiveHandler(archiver *archive.Archiver, logger *logger.Logger) *ArchiveHandler {
+	return &ArchiveHandler{
+		archiver: archiver,
+		logger:   logger,
+	}
+}
+
+func (h *ArchiveHandler) List(c *gin.Context) {
+	archives, err := h.archiver.List(c.Request.Context())
+	if err != nil {
+		logger.FromContext(c.Request.Context(), h.logger).Error("failed to list archives", "error", err)
+		respondWithError(c, http.StatusInternalServerError, "failed to list archives")
+		return
+	}
+
+	if archives == nil {
+		archives = []models.Archive{}
+	}
+	c.JSON(http.StatusOK, archives)
+}
+
+func (h *ArchiveHandler) Restore(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "invalid archive id")
+		return
+	}
+
+	restored, err := h.archiver.Restore(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, archive.ErrNotFound):
+		respondWithError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, archive.ErrRestored):
+		respondWithError(c, http.StatusConflict, err.Error())
+	case err != nil:
+		logger.FromContext(c.Request.Context(), h.logger).Error("failed to restore archive", "archive_id", id, "error", err)
+		respondWithError(c, http.StatusInternalServerError, "failed to restore archive")
+	default:
+		c.JSON(http.StatusOK, restored)
+	}
+}
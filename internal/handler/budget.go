@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	service *service.BudgetService
+	logger  *logger.Logger
+}
+
+func NewBudgetHandler(service *service.BudgetService, logger *logger.Logger) *BudgetHandler {
+	return &BudgetHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *BudgetHandler) Set(c *gin.Context) {
+	var req models.SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context(), h.logger).Error("invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	budget, err := h.service.Set(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		respondWithBudgetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+func (h *BudgetHandler) List(c *gin.Context) {
+	budgets, err := h.service.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithBudgetError(c, err)
+		return
+	}
+
+	if budgets == nil {
+		budgets = []models.Budget{}
+	}
+	c.JSON(http.StatusOK, budgets)
+}
+
+// Delete removes the budget of the category query parameter, or the overall
+// budget without it.
+func (h *BudgetHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id"), c.Query("category")); err != nil {
+		respondWithBudgetError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *BudgetHandler) Status(c *gin.Context) {
+	status, err := h.service.Status(c.Request.Context(), c.Param("id"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondWithBudgetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func respondWithBudgetError(c *gin.Context, err error) {
+	switch {
+	case respondWithValidation(c, err):
+	case errors.Is(err, service.ErrBudgetNotFound):
+		respondWithError(c, http.StatusNotFound, err.Error())
+	case service.IsArgumentError(err):
+		respondWithError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"subscription-service/internal/events"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	eventsBacklogLimit = 1000
+	eventsHeartbeat    = 15 * time.Second
+)
+
+type EventsHandler struct {
+	broker *events.Broker
+	logger *logger.Logger
+}
+
+func NewEventsHandler(broker *events.Broker, logger *logger.Logger) *EventsHandler {
+	return &EventsHandler{
+		broker: broker,
+		logger: logger,
+	}
+}
+
+// Stream sends subscription changes as server-sent events. A client that
+// reconnects with Last-Event-ID receives the events it missed first.
+func (h *EventsHandler) Stream(c *gin.Context) {
+	var userID *uuid.UUID
+	if raw := c.Query("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id format"})
+			return
+		}
+		userID = &parsed
+	}
+
+	var lastID int64
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("last_event_id")
+	}
+	if resume != "" {
+		parsed, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid last event id"})
+			return
+		}
+		lastID = parsed
+	}
+
+	log := logger.FromContext(c.Request.Context(), h.logger)
+
+	// Subscribe before reading the backlog so nothing committed in between
+	// is lost; duplicates are skipped below, as the broker and the backlog
+	// follow the same order.
+	sub := h.broker.Subscribe(tenant.FromContext(c.Request.Context()), userID)
+	defer h.broker.Unsubscribe(sub)
+
+	var backlog []models.Event
+	if resume != "" {
+		var err error
+		backlog, err = h.broker.Backlog(c.Request.Context(), lastID, userID, eventsBacklogLimit)
+		if err != nil {
+			log.Error("failed to load event backlog", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load events"})
+			return
+		}
+	}
+
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("failed to disable write deadline for event stream", "error", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	var last models.Event
+	for _, e := range backlog {
+		h.send(c, e)
+		last = e
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !events.Follows(e, last) {
+				continue
+			}
+			h.send(c, e)
+			last = e
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *EventsHandler) send(c *gin.Context, e models.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(e.ID, 10),
+		Event: e.Type,
+		Data:  e,
+	})
+}
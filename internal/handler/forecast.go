@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SubscriptionHandler) Forecast(c *gin.Context) {
+	months := 0
+	if raw := c.Query("months"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid months"})
+			return
+		}
+		months = parsed
+	}
+
+	filter := &models.SubscriptionFilter{
+		UserID:      c.Query("user_id"),
+		ServiceName: c.Query("service_name"),
+	}
+
+	forecast, err := h.service.Forecast(c.Request.Context(), filter, months)
+	if err != nil {
+		if service.IsArgumentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+func (h *SubscriptionHandler) SchedulePriceChange(c *gin.Context) {
+	var req models.SchedulePriceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	change, err := h.service.SchedulePriceChange(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		respondWithPriceChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, change)
+}
+
+func (h *SubscriptionHandler) ListPriceChanges(c *gin.Context) {
+	changes, err := h.service.ListPriceChanges(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithPriceChangeError(c, err)
+		return
+	}
+
+	if changes == nil {
+		changes = []models.PriceChange{}
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+func (h *SubscriptionHandler) DeletePriceChange(c *gin.Context) {
+	if err := h.service.DeletePriceChange(c.Request.Context(), c.Param("id"), c.Param("change_id")); err != nil {
+		respondWithPriceChangeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondWithPriceChangeError(c *gin.Context, err error) {
+	switch {
+	case respondWithValidation(c, err):
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrPriceChangeNotFound):
+		respondWithError(c, http.StatusNotFound, err.Error())
+	case service.IsArgumentError(err):
+		respondWithError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(c, http.StatusInternalServerError, err.Error())
+	}
+}
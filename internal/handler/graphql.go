@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/graphqlapi"
+	"subscription-service/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLHandler struct {
+	executor *graphqlapi.Executor
+	store    *config.Store
+	logger   *logger.Logger
+}
+
+func NewGraphQLHandler(executor *graphqlapi.Executor, store *config.Store, logger *logger.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+		store:    store,
+		logger:   logger,
+	}
+}
+
+// Serve executes a GraphQL query. Errors raised while resolving fields are
+// reported in the response body next to the partial data, as the GraphQL
+// spec requires, so the status is 200 for every well-formed request.
+func (h *GraphQLHandler) Serve(c *gin.Context) {
+	var req graphqlapi.Request
+	if err := c.ShouldBindJSON(&req); err != nil || req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	cfg := h.store.Get().GraphQL
+	result := h.executor.Execute(c.Request.Context(), req, graphqlapi.Limits{
+		MaxDepth:      cfg.MaxDepth,
+		MaxComplexity: cfg.MaxComplexity,
+	})
+	if result.HasErrors() {
+		logger.FromContext(c.Request.Context(), h.logger).Warn("graphql query failed", "errors", result.Errors)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -2,13 +2,23 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"subscription-service/internal/config"
 	"subscription-service/internal/logger"
 	"subscription-service/internal/models"
 	"subscription-service/internal/service"
+	"subscription-service/internal/tenant"
+	"subscription-service/internal/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SubscriptionHandler struct {
@@ -23,11 +33,45 @@ func NewSubscriptionHandler(service *service.SubscriptionService, logger *logger
 	}
 }
 
-func SetupRouter(h *SubscriptionHandler, logger *logger.Logger) *gin.Engine {
+// Handlers groups the HTTP handlers mounted by SetupRouter.
+type Handlers struct {
+	Subscriptions *SubscriptionHandler
+	Health        *HealthHandler
+	Webhooks      *WebhookHandler
+	Events        *EventsHandler
+	GraphQL       *GraphQLHandler
+	Budgets       *BudgetHandler
+	Archives      *ArchiveHandler
+	Privacy       *PrivacyHandler
+
+	// Tenants resolves the tenant of API requests. Without it every request
+	// belongs to the default tenant.
+	Tenants *tenant.Resolver
+}
+
+func SetupRouter(handlers Handlers, store *config.Store, logger *logger.Logger) *gin.Engine {
 	router := gin.New()
 
 	router.Use(gin.Recovery())
+
+	hh := handlers.Health
+	router.GET("/healthz", hh.Liveness)
+	router.GET("/readyz", hh.Readiness)
+
+	router.Use(otelgin.Middleware(tracing.ServiceName))
 	router.Use(LoggerMiddleware(logger))
+	router.Use(CORSMiddleware(store))
+	router.Use(RateLimitMiddleware(store))
+	if handlers.Tenants != nil {
+		router.Use(TenantMiddleware(handlers.Tenants))
+	}
+
+	if handlers.GraphQL != nil {
+		router.POST("/graphql", handlers.GraphQL.Serve)
+	}
+
+	h := handlers.Subscriptions
+	wh := handlers.Webhooks
 
 	api := router.Group("/api/v1")
 	{
@@ -36,36 +80,127 @@ func SetupRouter(h *SubscriptionHandler, logger *logger.Logger) *gin.Engine {
 			subscriptions.POST("/", h.Create)
 			subscriptions.GET("/", h.List)
 			subscriptions.GET("/total", h.GetTotalCost)
+			subscriptions.GET("/total/monthly", h.GetMonthlyBreakdown)
+			subscriptions.GET("/overlaps", h.ListOverlaps)
+			subscriptions.GET("/forecast", h.Forecast)
+			subscriptions.POST("/bulk/preview", h.PreviewBulk)
+			subscriptions.POST("/bulk/execute", h.ExecuteBulk)
+			subscriptions.GET("/events", handlers.Events.Stream)
 			subscriptions.GET("/:id", h.GetByID)
-			subscriptions.PUT("/:id", h.Update)
+			subscriptions.PUT("/:id", h.Replace)
+			subscriptions.PATCH("/:id", h.Patch)
 			subscriptions.DELETE("/:id", h.Delete)
+			subscriptions.GET("/:id/price-changes", h.ListPriceChanges)
+			subscriptions.POST("/:id/price-changes", h.SchedulePriceChange)
+			subscriptions.DELETE("/:id/price-changes/:change_id", h.DeletePriceChange)
+		}
+
+		api.GET("/services/suggest", FeatureMiddleware(store, config.FeatureServiceSuggestions), h.SuggestServices)
+
+		admin := AdminMiddleware(store)
+
+		ph := handlers.Privacy
+		api.GET("/users/:id/export", admin, ph.Export)
+		api.DELETE("/users/:id", admin, ph.Erase)
+		api.GET("/users/:id/statements/:month", h.Statement)
+
+		bh := handlers.Budgets
+		budget := api.Group("/users/:id/budget")
+		{
+			budget.GET("", bh.List)
+			budget.PUT("", bh.Set)
+			budget.DELETE("", bh.Delete)
+			budget.GET("/status", bh.Status)
+		}
+
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.POST("/", wh.Create)
+			webhooks.GET("/", wh.List)
+			webhooks.DELETE("/:id", wh.Delete)
+			webhooks.GET("/:id/deliveries", wh.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", wh.Redeliver)
+		}
+
+		ah := handlers.Archives
+		archives := api.Group("/admin/archives", admin)
+		{
+			archives.GET("", ah.List)
+			archives.POST("/:id/restore", ah.Restore)
 		}
 	}
 
 	return router
 }
 
-func LoggerMiddleware(logger *logger.Logger) gin.HandlerFunc {
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+func LoggerMiddleware(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger.Info("incoming request",
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		fields := []any{"request_id", requestID}
+		if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+			fields = append(fields, "trace_id", spanCtx.TraceID().String())
+		}
+		requestLogger := log.With(fields...)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
 			"method", c.Request.Method,
 			"path", c.Request.URL.Path,
+			"status", status,
+			"latency", time.Since(start),
+			"bytes", c.Writer.Size(),
 			"ip", c.ClientIP(),
-		)
-		c.Next()
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			requestLogger.Error("request completed", attrs...)
+		case status >= http.StatusBadRequest:
+			requestLogger.Warn("request completed", attrs...)
+		default:
+			requestLogger.Info("request completed", attrs...)
+		}
 	}
 }
 
+func (h *SubscriptionHandler) log(c *gin.Context) *logger.Logger {
+	return logger.FromContext(c.Request.Context(), h.logger)
+}
+
 func (h *SubscriptionHandler) Create(c *gin.Context) {
 	var req models.CreateSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("invalid request body", "error", err)
+		h.log(c).Error("invalid request body", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	subscription, err := h.service.Create(&req)
+	allowOverlap, ok := allowOverlapParam(c)
+	if !ok {
+		return
+	}
+	req.AllowOverlap = allowOverlap
+
+	subscription, err := h.service.Create(c.Request.Context(), &req)
 	if err != nil {
+		if respondWithConflict(c, err) || respondWithValidation(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -80,7 +215,7 @@ func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	subscription, err := h.service.GetByID(id)
+	subscription, err := h.service.GetByID(c.Request.Context(), id)
 	if err != nil {
 		if err.Error() == "subscription not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -93,33 +228,154 @@ func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 	c.JSON(http.StatusOK, subscription)
 }
 
-func (h *SubscriptionHandler) Update(c *gin.Context) {
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// Replace overwrites a subscription with the request body; fields left out
+// are cleared rather than kept.
+func (h *SubscriptionHandler) Replace(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
 		return
 	}
 
-	var req models.UpdateSubscriptionRequest
+	var req models.ReplaceSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("invalid request body", "error", err)
+		h.log(c).Error("invalid request body", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	subscription, err := h.service.Update(id, &req)
+	allowOverlap, ok := allowOverlapParam(c)
+	if !ok {
+		return
+	}
+	req.AllowOverlap = allowOverlap
+
+	subscription, err := h.service.Replace(c.Request.Context(), id, &req)
 	if err != nil {
-		if err.Error() == "subscription not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondWithUpdateError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, subscription)
 }
 
+// Patch applies a JSON Merge Patch or a JSON Patch, chosen by the content
+// type of the request, to a subscription.
+func (h *SubscriptionHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+
+	var format service.PatchFormat
+	switch c.ContentType() {
+	case mergePatchContentType:
+		format = service.MergePatch
+	case jsonPatchContentType:
+		format = service.JSONPatch
+	default:
+		c.Header("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported content type, expected " + mergePatchContentType + " or " + jsonPatchContentType})
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		h.log(c).Error("failed to read request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	allowOverlap, ok := allowOverlapParam(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.Patch(c.Request.Context(), id, format, patch, allowOverlap)
+	if err != nil {
+		respondWithUpdateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// PreviewBulk reports what a bulk update or deletion would affect and
+// returns the token that confirms it.
+func (h *SubscriptionHandler) PreviewBulk(c *gin.Context) {
+	var req models.BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	allowOverlap, ok := allowOverlapParam(c)
+	if !ok {
+		return
+	}
+	req.AllowOverlap = allowOverlap
+
+	preview, err := h.service.PreviewBulk(c.Request.Context(), &req)
+	if err != nil {
+		respondWithBulkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+func (h *SubscriptionHandler) ExecuteBulk(c *gin.Context) {
+	var req models.ExecuteBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	result, err := h.service.ExecuteBulk(c.Request.Context(), req.Token)
+	if err != nil {
+		respondWithBulkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func respondWithBulkError(c *gin.Context, err error) {
+	switch {
+	case respondWithConflict(c, err), respondWithValidation(c, err):
+	case errors.Is(err, service.ErrBulkOperationNotFound):
+		respondWithError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrBulkChanged):
+		respondWithError(c, http.StatusConflict, err.Error())
+	case service.IsArgumentError(err):
+		respondWithError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(c, http.StatusInternalServerError, "failed to run bulk operation")
+	}
+}
+
+func respondWithUpdateError(c *gin.Context, err error) {
+	switch {
+	case respondWithConflict(c, err), respondWithValidation(c, err):
+	case errors.Is(err, service.ErrNotFound):
+		respondWithError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrPatchTestFailed):
+		respondWithError(c, http.StatusConflict, err.Error())
+	case service.IsArgumentError(err):
+		respondWithError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(c, http.StatusInternalServerError, "failed to update subscription")
+	}
+}
+
 func (h *SubscriptionHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -127,7 +383,7 @@ func (h *SubscriptionHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -141,10 +397,15 @@ func (h *SubscriptionHandler) List(c *gin.Context) {
 		ServiceName: c.Query("service_name"),
 		StartMonth:  c.Query("start_month"),
 		EndMonth:    c.Query("end_month"),
+		Query:       c.Query("q"),
 	}
 
-	subscriptions, err := h.service.List(filter)
+	subscriptions, err := h.service.List(c.Request.Context(), filter)
 	if err != nil {
+		if service.IsArgumentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -160,7 +421,7 @@ func (h *SubscriptionHandler) GetTotalCost(c *gin.Context) {
 		EndMonth:    c.Query("end_month"),
 	}
 
-	total, err := h.service.GetTotalCost(filter)
+	total, err := h.service.GetTotalCost(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -169,6 +430,109 @@ func (h *SubscriptionHandler) GetTotalCost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"total": total})
 }
 
+func (h *SubscriptionHandler) SuggestServices(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions, err := h.service.SuggestServices(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if service.IsArgumentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if suggestions == nil {
+		suggestions = []models.ServiceSuggestion{}
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+func (h *SubscriptionHandler) ListOverlaps(c *gin.Context) {
+	filter := &models.SubscriptionFilter{
+		UserID:      c.Query("user_id"),
+		ServiceName: c.Query("service_name"),
+	}
+
+	overlaps, err := h.service.ListOverlaps(c.Request.Context(), filter)
+	if err != nil {
+		if service.IsArgumentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if overlaps == nil {
+		overlaps = []models.SubscriptionOverlap{}
+	}
+	c.JSON(http.StatusOK, overlaps)
+}
+
+func (h *SubscriptionHandler) GetMonthlyBreakdown(c *gin.Context) {
+	filter := &models.SubscriptionFilter{
+		UserID:      c.Query("user_id"),
+		ServiceName: c.Query("service_name"),
+		StartMonth:  c.Query("start_month"),
+		EndMonth:    c.Query("end_month"),
+	}
+
+	breakdown, err := h.service.GetMonthlyBreakdown(c.Request.Context(), filter)
+	if err != nil {
+		if service.IsArgumentError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// allowOverlapParam reads the allow_overlap query parameter, answering 400
+// itself when it is not a boolean.
+func allowOverlapParam(c *gin.Context) (bool, bool) {
+	raw := c.Query("allow_overlap")
+	if raw == "" {
+		return false, true
+	}
+	allow, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allow_overlap value"})
+		return false, false
+	}
+	return allow, true
+}
+
+func respondWithConflict(c *gin.Context, err error) bool {
+	var conflict *service.ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": conflict.Message, "conflicts": conflict.Conflicts})
+	return true
+}
+
+func respondWithValidation(c *gin.Context, err error) bool {
+	var validation *service.ValidationError
+	if !errors.As(err, &validation) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "fields": validation.Fields})
+	return true
+}
+
 func respondWithError(c *gin.Context, code int, message string) {
 	c.JSON(code, gin.H{"error": message})
 }
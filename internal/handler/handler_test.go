@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	log, err := logger.New("error", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(LoggerMiddleware(log))
+	router.GET("/ping", func(c *gin.Context) {
+		if logger.FromContext(c.Request.Context(), nil) == nil {
+			t.Error("Expected request logger in context")
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestLoggerMiddlewareGeneratesRequestID(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	if w.Header().Get(RequestIDHeader) == "" {
+		t.Error("Expected generated request id in response")
+	}
+}
+
+func TestLoggerMiddlewarePropagatesRequestID(t *testing.T) {
+	router := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("Expected request id abc-123, got %s", got)
+	}
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	router := gin.New()
+	router.DELETE("/users/:id", AdminMiddleware(config.NewStore("", cfg)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		configured string
+		sent       string
+		expected   int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "secret", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "other", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		cfg.Admin.Token = tt.configured
+		req := httptest.NewRequest(http.MethodDelete, "/users/"+uuid.NewString(), nil)
+		if tt.sent != "" {
+			req.Header.Set(AdminTokenHeader, tt.sent)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("Expected status %d with token %q sent for %q, got %d", tt.expected, tt.sent, tt.configured, w.Code)
+		}
+	}
+}
+
+func TestWriteUserExport(t *testing.T) {
+	export := &models.UserExport{
+		UserID:        uuid.New(),
+		ExportedAt:    time.Now(),
+		Subscriptions: []models.Subscription{{ID: uuid.New(), ServiceName: "Netflix", Price: 400}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeUserExport(&buf, export); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(data)
+	}
+
+	if len(contents) != 7 {
+		t.Errorf("Expected 7 files, got %d", len(contents))
+	}
+	if !strings.Contains(contents["subscriptions.json"], "Netflix") {
+		t.Errorf("Expected the subscription in subscriptions.json, got %s", contents["subscriptions.json"])
+	}
+	if strings.TrimSpace(contents["budgets.json"]) != "[]" {
+		t.Errorf("Expected an empty list in budgets.json, got %s", contents["budgets.json"])
+	}
+	if !strings.Contains(contents["manifest.json"], `"subscriptions.json": 1`) {
+		t.Errorf("Expected record counts in manifest.json, got %s", contents["manifest.json"])
+	}
+}
+
+func TestPatchRejectsUnsupportedContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &SubscriptionHandler{}
+	router := gin.New()
+	router.PATCH("/subscriptions/:id", h.Patch)
+
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+uuid.NewString(), strings.NewReader(`{"price": 500}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", w.Code)
+	}
+	if w.Header().Get("Accept-Patch") == "" {
+		t.Error("Expected the supported patch formats in Accept-Patch")
+	}
+}
+
+func TestStatementRejectsUnacceptableFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &SubscriptionHandler{}
+	router := gin.New()
+	router.GET("/users/:id/statements/:month", h.Statement)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString()+"/statements/10-2026", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected status 406, got %d", w.Code)
+	}
+}
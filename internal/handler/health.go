@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"subscription-service/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Ready(c.Request.Context())
+	if report.Status != health.StatusOK {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
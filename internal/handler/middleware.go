@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const rateLimitClientTTL = 3 * time.Minute
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitMiddleware applies a per-client token bucket. Limits are read
+// from the store on every request so a config reload takes effect at once.
+func RateLimitMiddleware(store *config.Store) gin.HandlerFunc {
+	var (
+		mu          sync.Mutex
+		settings    config.RateLimitConfig
+		clients     = make(map[string]*rateLimitClient)
+		lastCleanup = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		cfg := store.Get().RateLimit
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+
+		mu.Lock()
+		if cfg != settings {
+			settings = cfg
+			clients = make(map[string]*rateLimitClient)
+		}
+		if now.Sub(lastCleanup) > rateLimitClientTTL {
+			for ip, client := range clients {
+				if now.Sub(client.lastSeen) > rateLimitClientTTL {
+					delete(clients, ip)
+				}
+			}
+			lastCleanup = now
+		}
+
+		client, ok := clients[c.ClientIP()]
+		if !ok {
+			client = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), cfg.Burst)}
+			clients[c.ClientIP()] = client
+		}
+		client.lastSeen = now
+		allowed := client.limiter.AllowN(now, 1)
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CORSMiddleware allows cross-origin requests from the configured origins.
+// A single "*" entry allows any origin.
+func CORSMiddleware(store *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Origin")
+		if !originAllowed(store.Get().CORS.AllowedOrigins, origin) {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Expose-Headers", RequestIDHeader)
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			allowed := "Content-Type, Authorization, " + RequestIDHeader + ", traceparent, tracestate"
+			if header := store.Get().Tenancy.Header; header != "" {
+				allowed += ", " + header
+			}
+			c.Header("Access-Control-Allow-Headers", allowed)
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminTokenHeader carries the token of the administrative endpoints.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminMiddleware lets through only requests with the admin token of the
+// live configuration, so that the token can be rotated with a reload.
+func AdminMiddleware(store *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := store.Get().Admin.Token
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(AdminTokenHeader)), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// FeatureMiddleware answers 404 while the feature toggle name is off in the
+// live configuration, so that it can be switched with a reload.
+func FeatureMiddleware(store *config.Store, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !store.Get().FeatureEnabled(name) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "feature is disabled"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TenantMiddleware resolves the tenant of the request and stores it in the
+// request context, where the repositories pick it up.
+func TenantMiddleware(resolver *tenant.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := resolver.Resolve(c.GetHeader(resolver.Header()), c.GetHeader("Authorization"))
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, tenant.ErrInvalidToken) {
+				status = http.StatusUnauthorized
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
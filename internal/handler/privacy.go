@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	service *service.PrivacyService
+	logger  *logger.Logger
+}
+
+func NewPrivacyHandler(service *service.PrivacyService, logger *logger.Logger) *PrivacyHandler {
+	return &PrivacyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Export sends everything stored about a user as a ZIP of JSON files.
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	export, err := h.service.Export(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithPrivacyError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writeUserExport(&buf, export); err != nil {
+		logger.FromContext(c.Request.Context(), h.logger).Error("failed to write user export", "error", err)
+		respondWithError(c, http.StatusInternalServerError, "failed to export user data")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.zip"`, export.UserID))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// Erase removes everything stored about a user and returns the receipt of
+// the erasure.
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	receipt, err := h.service.Erase(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+func respondWithPrivacyError(c *gin.Context, err error) {
+	if service.IsArgumentError(err) {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithError(c, http.StatusInternalServerError, err.Error())
+}
+
+// writeUserExport writes export as a ZIP holding one JSON file per kind of
+// data and a manifest listing them with their number of records.
+func writeUserExport(w io.Writer, export *models.UserExport) error {
+	files := []struct {
+		name    string
+		data    any
+		records int
+	}{
+		{"subscriptions.json", export.Subscriptions, len(export.Subscriptions)},
+		{"price_changes.json", export.PriceChanges, len(export.PriceChanges)},
+		{"archived_subscriptions.json", export.ArchivedSubscriptions, len(export.ArchivedSubscriptions)},
+		{"budgets.json", export.Budgets, len(export.Budgets)},
+		{"events.json", export.Events, len(export.Events)},
+		{"reminders.json", export.Reminders, len(export.Reminders)},
+	}
+
+	manifest := struct {
+		UserID     string         `json:"user_id"`
+		ExportedAt time.Time      `json:"exported_at"`
+		Files      map[string]int `json:"files"`
+	}{
+		UserID:     export.UserID.String(),
+		ExportedAt: export.ExportedAt,
+		Files:      make(map[string]int, len(files)),
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		manifest.Files[f.name] = f.records
+		if f.records == 0 {
+			f.data = []struct{}{}
+		}
+		if err := writeZipJSON(zw, f.name, f.data); err != nil {
+			return err
+		}
+	}
+	if err := writeZipJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"subscription-service/internal/service"
+	"subscription-service/internal/statement"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statementFormats are offered in this order; the first one is the default
+// when the request accepts anything.
+var statementFormats = []string{statement.ContentTypeCSV, statement.ContentTypeHTML, statement.ContentTypePDF, gin.MIMEJSON}
+
+// Statement sends the monthly statement of a user in the format chosen by
+// the Accept header.
+func (h *SubscriptionHandler) Statement(c *gin.Context) {
+	format := c.NegotiateFormat(statementFormats...)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "statements are available as text/csv, text/html, application/pdf or application/json"})
+		return
+	}
+
+	st, err := h.service.Statement(c.Request.Context(), c.Param("id"), c.Param("month"))
+	if err != nil {
+		if service.IsArgumentError(err) {
+			respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "failed to get statement")
+		return
+	}
+
+	if format == gin.MIMEJSON {
+		c.JSON(http.StatusOK, st)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := statement.Write(&buf, format, st); err != nil {
+		h.log(c).Error("failed to render statement", "format", format, "error", err)
+		respondWithError(c, http.StatusInternalServerError, "failed to render statement")
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.%s", st.UserID, st.Month, statement.Extension(format))
+	contentType := format
+	switch format {
+	case statement.ContentTypeHTML:
+		c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+		contentType += "; charset=utf-8"
+	case statement.ContentTypeCSV:
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		contentType += "; charset=utf-8"
+	default:
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
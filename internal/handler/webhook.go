@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+	logger  *logger.Logger
+}
+
+func NewWebhookHandler(service *service.WebhookService, logger *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c.Request.Context(), h.logger).Error("invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	hook, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), c.Param("id"), c.Query("status"))
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	if err := h.service.Redeliver(c.Request.Context(), c.Param("id"), deliveryID); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": models.DeliveryStatusPending})
+}
+
+func respondWithServiceError(c *gin.Context, err error) {
+	if strings.HasSuffix(err.Error(), "not found") {
+		respondWithError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	respondWithError(c, http.StatusBadRequest, err.Error())
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker aggregates readiness checks. It reports not ready once shutdown
+// has begun regardless of the individual checks.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+	timeout      time.Duration
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) StartShutdown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks)+1)}
+	if c.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Error: "server is shutting down"}
+	}
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			if err := check(ctx); err != nil {
+				results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+				return
+			}
+			results[i] = CheckResult{Status: StatusOK}
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// Worker tracks whether a background worker loop is alive. Workers call
+// Beat on every iteration; the check fails when no beat arrived within
+// the allowed interval.
+type Worker struct {
+	lastBeat atomic.Int64
+	maxAge   time.Duration
+}
+
+func NewWorker(maxAge time.Duration) *Worker {
+	w := &Worker{maxAge: maxAge}
+	w.Beat()
+	return w
+}
+
+func (w *Worker) Beat() {
+	w.lastBeat.Store(time.Now().UnixNano())
+}
+
+func (w *Worker) Stop() {
+	w.lastBeat.Store(0)
+}
+
+func (w *Worker) Check(ctx context.Context) error {
+	last := w.lastBeat.Load()
+	if last == 0 {
+		return errors.New("worker is not running")
+	}
+	if time.Since(time.Unix(0, last)) > w.maxAge {
+		return errors.New("worker is stalled")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReady(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+
+	report := checker.Ready(context.Background())
+	if report.Status != StatusOK {
+		t.Errorf("Expected status ok, got %s", report.Status)
+	}
+
+	checker.Add("migrations", func(ctx context.Context) error { return errors.New("schema version 0, expected 1") })
+
+	report = checker.Ready(context.Background())
+	if report.Status != StatusFail {
+		t.Errorf("Expected status fail, got %s", report.Status)
+	}
+	if report.Checks["migrations"].Error == "" {
+		t.Error("Expected migrations check error to be reported")
+	}
+}
+
+func TestCheckerShutdown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.StartShutdown()
+
+	report := checker.Ready(context.Background())
+	if report.Status != StatusFail {
+		t.Errorf("Expected status fail during shutdown, got %s", report.Status)
+	}
+}
+
+func TestWorkerCheck(t *testing.T) {
+	worker := NewWorker(time.Minute)
+	if err := worker.Check(context.Background()); err != nil {
+		t.Errorf("Expected running worker, got %v", err)
+	}
+
+	worker.Stop()
+	if err := worker.Check(context.Background()); err == nil {
+		t.Error("Expected error for stopped worker")
+	}
+}
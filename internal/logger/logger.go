@@ -1,41 +1,90 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 )
 
 type Logger struct {
 	*slog.Logger
+	level *slog.LevelVar
 }
 
-func (l *Logger) Fatal(s string, param2 string, err error) {
-	panic("unimplemented")
+type contextKey struct{}
+
+func New(level, format string) (*Logger, error) {
+	return NewWithWriter(os.Stdout, level, format)
+}
+
+// NewWithWriter is like New but writes to w instead of stdout.
+func NewWithWriter(w io.Writer, level, format string) (*Logger, error) {
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(parseLevel(level))
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	logger := slog.New(handler)
+	return &Logger{Logger: logger, level: logLevel}, nil
 }
 
-func New(level string) (*Logger, error) {
-	var logLevel slog.Level
+func parseLevel(level string) slog.Level {
 	switch level {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
+		return slog.LevelError
 	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
 	}
+}
 
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	})
+// SetLevel changes the minimum level of this logger and every logger
+// derived from it with With.
+func (l *Logger) SetLevel(level string) {
+	l.level.Set(parseLevel(level))
+}
 
-	logger := slog.New(handler)
-	return &Logger{logger}, nil
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{Logger: l.Logger.With(args...), level: l.level}
 }
 
 func (l *Logger) Sync() error {
 	return nil
 }
+
+// WithContext returns a copy of ctx carrying the given request-scoped logger.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
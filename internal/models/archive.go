@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Archive is a file of subscriptions moved out of the subscriptions table
+// because they ended before EndedBefore.
+type Archive struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name"`
+	Subscriptions int        `db:"subscriptions" json:"subscriptions"`
+	EndedBefore   time.Time  `db:"ended_before" json:"ended_before"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	RestoredAt    *time.Time `db:"restored_at" json:"restored_at,omitempty"`
+}
+
+// ArchivedSubscription is one record of an archive file.
+type ArchivedSubscription struct {
+	Subscription
+	PriceChanges []PriceChange `json:"price_changes,omitempty"`
+}
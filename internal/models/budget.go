@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const EventBudgetExceeded = "budget.exceeded"
+
+// Budget caps the monthly spend of a user. A budget without a category
+// covers every subscription; a category budget covers the listed services.
+type Budget struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	Category  string    `db:"category" json:"category,omitempty"`
+	Services  []string  `db:"services" json:"services,omitempty"`
+	Amount    int       `db:"amount" json:"amount"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Covers reports whether subscriptions to serviceName count against b.
+func (b *Budget) Covers(serviceName string) bool {
+	if b.Category == "" {
+		return true
+	}
+	for _, s := range b.Services {
+		if strings.EqualFold(s, serviceName) {
+			return true
+		}
+	}
+	return false
+}
+
+type SetBudgetRequest struct {
+	Category string   `json:"category"`
+	Services []string `json:"services"`
+	Amount   int      `json:"amount"`
+}
+
+type BudgetMonth struct {
+	Month      string `json:"month"`
+	Spend      int    `json:"spend"`
+	OverBudget bool   `json:"over_budget"`
+	Overspend  int    `json:"overspend"`
+}
+
+type BudgetStatus struct {
+	Budget           Budget        `json:"budget"`
+	Months           []BudgetMonth `json:"months"`
+	OverBudgetMonths int           `json:"over_budget_months"`
+}
+
+type UserBudgetStatus struct {
+	UserID  uuid.UUID      `json:"user_id"`
+	Budgets []BudgetStatus `json:"budgets"`
+}
+
+// BudgetAlert is the payload of a budget.exceeded event: the months that
+// the subscription pushed over the budget, with their projected spend.
+type BudgetAlert struct {
+	UserID         uuid.UUID     `json:"user_id"`
+	SubscriptionID uuid.UUID     `json:"subscription_id"`
+	Category       string        `json:"category,omitempty"`
+	Amount         int           `json:"amount"`
+	Months         []MonthlyCost `json:"months"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BulkActionUpdate = "update"
+	BulkActionDelete = "delete"
+)
+
+// BulkUpdate lists the fields a bulk update sets; the others are left as
+// they are. An empty end date makes the subscriptions open ended.
+type BulkUpdate struct {
+	Price   *int    `json:"price,omitempty"`
+	EndDate *string `json:"end_date,omitempty"`
+}
+
+// BulkRequest asks for the preview of an update or deletion of every
+// subscription matching Filter.
+type BulkRequest struct {
+	Action string             `json:"action"`
+	Filter SubscriptionFilter `json:"filter"`
+	Update *BulkUpdate        `json:"update,omitempty"`
+
+	// AllowOverlap lets an update of the end date make subscriptions of the
+	// same user and service overlap.
+	AllowOverlap bool `json:"-"`
+}
+
+// BulkPreview shows what a bulk operation would affect. Token confirms the
+// operation; it is missing when nothing matches.
+type BulkPreview struct {
+	Token     *uuid.UUID     `json:"token,omitempty"`
+	Action    string         `json:"action"`
+	Matched   int            `json:"matched"`
+	Sample    []Subscription `json:"sample"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+
+	// Overlaps counts the pairs of subscriptions an update of the end date
+	// makes overlap, allowed with allow_overlap; OverlapSample lists some.
+	Overlaps      int                   `json:"overlaps,omitempty"`
+	OverlapSample []SubscriptionOverlap `json:"overlap_sample,omitempty"`
+}
+
+type ExecuteBulkRequest struct {
+	Token string `json:"token"`
+}
+
+type BulkResult struct {
+	Action   string `json:"action"`
+	Affected int    `json:"affected"`
+}
+
+// BulkOperation is a validated bulk operation waiting for confirmation. Its
+// ID is the confirmation token handed out with the preview.
+type BulkOperation struct {
+	ID           uuid.UUID          `json:"-"`
+	Action       string             `json:"action"`
+	Filter       SubscriptionFilter `json:"filter"`
+	Price        *int               `json:"price,omitempty"`
+	SetEndDate   bool               `json:"set_end_date,omitempty"`
+	EndDate      *time.Time         `json:"end_date,omitempty"`
+	AllowOverlap bool               `json:"allow_overlap,omitempty"`
+	Matched      int                `json:"-"`
+	ExpiresAt    time.Time          `json:"-"`
+}
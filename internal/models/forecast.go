@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceChange sets the price of a subscription from EffectiveDate onwards.
+type PriceChange struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	SubscriptionID uuid.UUID `db:"subscription_id" json:"subscription_id"`
+	EffectiveDate  time.Time `db:"effective_date" json:"effective_date"`
+	Price          int       `db:"price" json:"price"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+type SchedulePriceChangeRequest struct {
+	EffectiveDate string `json:"effective_date"`
+	Price         int    `json:"price"`
+}
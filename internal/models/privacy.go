@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserExport is everything stored about a user: their subscriptions, live
+// and archived, budgets, event history and reminders.
+type UserExport struct {
+	UserID                uuid.UUID              `json:"user_id"`
+	ExportedAt            time.Time              `json:"exported_at"`
+	Subscriptions         []Subscription         `json:"subscriptions"`
+	PriceChanges          []PriceChange          `json:"price_changes"`
+	ArchivedSubscriptions []ArchivedSubscription `json:"archived_subscriptions"`
+	Budgets               []Budget               `json:"budgets"`
+	Events                []Event                `json:"events"`
+	Reminders             []Reminder             `json:"reminders"`
+}
+
+// ErasureReceipt records what the erasure of a user's data removed. Events
+// and archived monthly totals are kept anonymized, so that tenant-wide
+// history and totals stay intact.
+type ErasureReceipt struct {
+	ID                           uuid.UUID `json:"id"`
+	UserID                       uuid.UUID `json:"user_id"`
+	ErasedAt                     time.Time `json:"erased_at"`
+	SubscriptionsDeleted         int       `json:"subscriptions_deleted"`
+	PriceChangesDeleted          int       `json:"price_changes_deleted"`
+	RemindersDeleted             int       `json:"reminders_deleted"`
+	BudgetsDeleted               int       `json:"budgets_deleted"`
+	EventsAnonymized             int       `json:"events_anonymized"`
+	ArchivedTotalsAnonymized     int       `json:"archived_totals_anonymized"`
+	ArchivedSubscriptionsDeleted int       `json:"archived_subscriptions_deleted"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReminderKindExpiry  = "expiry"
+	ReminderKindRenewal = "renewal"
+
+	ReminderStatusPending = "pending"
+	ReminderStatusSent    = "sent"
+	ReminderStatusFailed  = "failed"
+)
+
+type Reminder struct {
+	ID             int64      `db:"id" json:"id"`
+	SubscriptionID uuid.UUID  `db:"subscription_id" json:"subscription_id"`
+	Kind           string     `db:"kind" json:"kind"`
+	DueDate        time.Time  `db:"due_date" json:"due_date"`
+	Channel        string     `db:"channel" json:"channel"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	LastError      *string    `db:"last_error" json:"last_error,omitempty"`
+	ClaimedAt      time.Time  `db:"claimed_at" json:"claimed_at"`
+	SentAt         *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatementLine is one charge of a statement. Archived subscriptions are
+// only known by their monthly totals, so they appear as one line per
+// service without a subscription or period.
+type StatementLine struct {
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	ServiceName    string     `json:"service_name"`
+	StartDate      *time.Time `json:"start_date,omitempty"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	Amount         int        `json:"amount"`
+	Archived       bool       `json:"archived,omitempty"`
+}
+
+// Statement lists what a user was charged in one month.
+type Statement struct {
+	UserID      uuid.UUID       `json:"user_id"`
+	Month       string          `json:"month"`
+	Lines       []StatementLine `json:"lines"`
+	Total       int             `json:"total"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
@@ -15,26 +15,64 @@ type Subscription struct {
 	EndDate     *time.Time `db:"end_date" json:"end_date,omitempty"`
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	TenantID    string     `db:"tenant_id" json:"-"`
 }
 
 type CreateSubscriptionRequest struct {
-	ServiceName string `json:"service_name" binding:"required"`
-	Price       int    `json:"price" binding:"required,min=0"`
-	UserID      string `json:"user_id" binding:"required,uuid"`
-	StartDate   string `json:"start_date" binding:"required"`
+	ServiceName string `json:"service_name"`
+	Price       int    `json:"price"`
+	UserID      string `json:"user_id"`
+	StartDate   string `json:"start_date"`
 	EndDate     string `json:"end_date,omitempty"`
+
+	// AllowOverlap skips the check for an existing subscription of the user
+	// to the same service in an overlapping period.
+	AllowOverlap bool `json:"-"`
 }
 
-type UpdateSubscriptionRequest struct {
-	ServiceName string  `json:"service_name"`
-	Price       *int    `json:"price"`
-	StartDate   string  `json:"start_date"`
-	EndDate     *string `json:"end_date"`
+// ReplaceSubscriptionRequest is the full editable representation of a
+// subscription: a replacement clears the end date when it is left out, and
+// patches are applied to it. The user of a subscription cannot change, so
+// user_id may be left out but must match when given.
+type ReplaceSubscriptionRequest struct {
+	ServiceName string `json:"service_name"`
+	Price       *int   `json:"price"`
+	UserID      string `json:"user_id,omitempty"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date,omitempty"`
+
+	AllowOverlap bool `json:"-"`
 }
 
 type SubscriptionFilter struct {
-	UserID      string `form:"user_id"`
-	ServiceName string `form:"service_name"`
-	StartMonth  string `form:"start_month"`
-	EndMonth    string `form:"end_month"`
+	UserID      string `form:"user_id" json:"user_id,omitempty"`
+	ServiceName string `form:"service_name" json:"service_name,omitempty"`
+	StartMonth  string `form:"start_month" json:"start_month,omitempty"`
+	EndMonth    string `form:"end_month" json:"end_month,omitempty"`
+
+	// Query matches service names by case-insensitive substring or trigram
+	// similarity; List then orders results by relevance.
+	Query string `form:"q" json:"q,omitempty"`
+}
+
+type MonthlyCost struct {
+	Month string `json:"month"`
+	Total int    `json:"total"`
+}
+
+type MonthlyBreakdown struct {
+	Months []MonthlyCost `json:"months"`
+	Total  int           `json:"total"`
+}
+
+// SubscriptionOverlap is a pair of subscriptions of one user to the same
+// service with intersecting periods.
+type SubscriptionOverlap struct {
+	First  Subscription `json:"first"`
+	Second Subscription `json:"second"`
+}
+
+type ServiceSuggestion struct {
+	Name          string `json:"name"`
+	Subscriptions int    `json:"subscriptions"`
 }
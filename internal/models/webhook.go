@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+var EventTypes = []string{EventSubscriptionCreated, EventSubscriptionUpdated, EventSubscriptionDeleted, EventBudgetExceeded}
+
+// Event is a subscription change, or a consequence of one such as a budget
+// alert, recorded in the outbox in the same transaction as the change itself.
+type Event struct {
+	ID             int64           `db:"id" json:"id"`
+	Type           string          `db:"event_type" json:"type"`
+	SubscriptionID uuid.UUID       `db:"subscription_id" json:"subscription_id"`
+	UserID         uuid.UUID       `db:"user_id" json:"user_id"`
+	Payload        json.RawMessage `db:"payload" json:"data"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	TenantID       string          `db:"tenant_id" json:"-"`
+
+	// TxID is the transaction that stored the event. Events are delivered
+	// in (TxID, ID) order, which unlike ID alone no later commit precedes.
+	TxID uint64 `db:"txid" json:"-"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	URL       string    `db:"url" json:"url"`
+	Secret    string    `db:"secret" json:"-"`
+	Events    []string  `db:"events" json:"events"`
+	Active    bool      `db:"active" json:"active"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `db:"id" json:"id"`
+	WebhookID      uuid.UUID  `db:"webhook_id" json:"webhook_id"`
+	EventID        int64      `db:"event_id" json:"event_id"`
+	EventType      string     `db:"event_type" json:"event_type"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code" json:"last_status_code,omitempty"`
+	LastError      *string    `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery is a claimed delivery together with what is needed to send it.
+type PendingDelivery struct {
+	ID       int64
+	Attempts int
+	URL      string
+	Secret   string
+	Event    Event
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
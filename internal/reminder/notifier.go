@@ -0,0 +1,213 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const SignatureHeader = "X-Signature-SHA256"
+
+type Notification struct {
+	Kind           string    `json:"kind"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ServiceName    string    `json:"service_name"`
+	Price          int       `json:"price"`
+	DueDate        time.Time `json:"due_date"`
+}
+
+func (n Notification) Subject() string {
+	if n.Kind == models.ReminderKindExpiry {
+		return fmt.Sprintf("Subscription %s ends on %s", n.ServiceName, n.DueDate.Format("02.01.2006"))
+	}
+	return fmt.Sprintf("Subscription %s renews on %s", n.ServiceName, n.DueDate.Format("02.01.2006"))
+}
+
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifiers builds the channels listed in the reminders configuration.
+func NewNotifiers(cfg config.RemindersConfig, log *logger.Logger) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(cfg.Channels))
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case "log":
+			notifiers = append(notifiers, &LogNotifier{logger: log})
+		case "webhook":
+			notifiers = append(notifiers, &WebhookNotifier{
+				url:    cfg.Webhook.URL,
+				secret: cfg.Webhook.Secret,
+				client: &http.Client{Timeout: cfg.Webhook.Timeout},
+			})
+		case "smtp":
+			notifiers = append(notifiers, &SMTPNotifier{cfg: cfg.SMTP})
+		default:
+			return nil, fmt.Errorf("unknown reminder channel %q", channel)
+		}
+	}
+	return notifiers, nil
+}
+
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	logger.FromContext(ctx, n.logger).Info("subscription reminder",
+		"kind", notification.Kind,
+		"subscription_id", notification.SubscriptionID,
+		"user_id", notification.UserID,
+		"service_name", notification.ServiceName,
+		"due_date", notification.DueDate.Format(time.DateOnly),
+	)
+	return nil
+}
+
+// WebhookNotifier posts the notification as JSON. When a secret is set the
+// body is signed with HMAC-SHA256 and the hex digest sent in SignatureHeader.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier mails reminders to the configured recipients. Subscriptions
+// carry no contact details, so recipients are fixed in the configuration.
+type SMTPNotifier struct {
+	cfg config.SMTPConfig
+}
+
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", notification.Subject())
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "User: %s\r\nService: %s\r\nPrice: %d\r\nDate: %s\r\n",
+		notification.UserID, notification.ServiceName, notification.Price, notification.DueDate.Format(time.DateOnly))
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The deadline bounds a server that stalls mid-conversation; closing the
+	// connection when ctx ends interrupts it on shutdown.
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	return n.send(conn, msg.String())
+}
+
+// send runs the conversation smtp.SendMail would over conn.
+func (n *SMTPNotifier) send(conn net.Conn, msg string) error {
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range n.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
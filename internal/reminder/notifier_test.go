@@ -0,0 +1,51 @@
+package reminder
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscription-service/internal/config"
+)
+
+func TestSMTPNotifierTimesOutOnStalledServer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	stalled := make(chan struct{})
+	defer close(stalled)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		<-stalled
+		conn.Close()
+	}()
+
+	host, port, _ := net.SplitHostPort(lis.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	n := &SMTPNotifier{cfg: config.SMTPConfig{
+		Host:    host,
+		Port:    portNum,
+		From:    "reminders@example.com",
+		To:      []string{"ops@example.com"},
+		Timeout: 100 * time.Millisecond,
+	}}
+
+	done := make(chan error, 1)
+	go func() { done <- n.Notify(context.Background(), Notification{DueDate: date(2025, 1, 1)}) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected error from a server that never greets")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Notify to give up after its timeout")
+	}
+}
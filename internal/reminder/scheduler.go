@@ -0,0 +1,193 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/health"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+)
+
+type due struct {
+	kind string
+	date time.Time
+}
+
+// Scheduler periodically looks for subscriptions that expire or renew within
+// the configured horizon and notifies every channel once per due date.
+// Deduplication is done in the reminders table, so several replicas can run
+// the scheduler at the same time.
+type Scheduler struct {
+	repo      *repository.ReminderRepository
+	notifiers []Notifier
+	cfg       config.RemindersConfig
+	logger    *logger.Logger
+	worker    *health.Worker
+	now       func() time.Time
+}
+
+func NewScheduler(repo *repository.ReminderRepository, notifiers []Notifier, cfg config.RemindersConfig, logger *logger.Logger) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		notifiers: notifiers,
+		cfg:       cfg,
+		logger:    logger,
+		worker:    health.NewWorker(3 * cfg.Interval),
+		now:       time.Now,
+	}
+}
+
+// Worker exposes the scheduler heartbeat for readiness checks.
+func (s *Scheduler) Worker() *health.Worker {
+	return s.worker
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.worker.Stop()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("reminder run failed", "error", err)
+		}
+		s.worker.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	today := truncateToDay(s.now())
+	until := today.Add(s.cfg.Horizon)
+
+	subscriptions, err := s.repo.ListDue(ctx, today, until, renewalDays(today, until))
+	if err != nil {
+		return err
+	}
+
+	var sent, failed int
+	for _, sub := range subscriptions {
+		for _, d := range dueReminders(sub, today, until) {
+			for _, notifier := range s.notifiers {
+				ok, err := s.deliver(ctx, sub, d, notifier)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					failed++
+					continue
+				}
+				if ok {
+					sent++
+				}
+			}
+		}
+	}
+
+	if sent > 0 || failed > 0 {
+		s.logger.Info("reminders processed", "sent", sent, "failed", failed)
+	}
+	return nil
+}
+
+func (s *Scheduler) deliver(ctx context.Context, sub models.Subscription, d due, notifier Notifier) (bool, error) {
+	rem, err := s.repo.Claim(ctx, sub.ID, d.kind, d.date, notifier.Name(), s.cfg.MaxAttempts, s.cfg.ClaimTimeout)
+	if err != nil {
+		s.logger.Error("failed to claim reminder", "subscription_id", sub.ID, "error", err)
+		return false, err
+	}
+	if rem == nil {
+		return false, nil
+	}
+
+	err = notifier.Notify(ctx, Notification{
+		Kind:           d.kind,
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		ServiceName:    sub.ServiceName,
+		Price:          sub.Price,
+		DueDate:        d.date,
+	})
+	if err != nil {
+		s.logger.Warn("failed to send reminder",
+			"subscription_id", sub.ID, "channel", notifier.Name(), "attempt", rem.Attempts, "error", err)
+		if markErr := s.repo.MarkFailed(ctx, rem.ID, err.Error()); markErr != nil {
+			s.logger.Error("failed to record reminder failure", "id", rem.ID, "error", markErr)
+		}
+		return false, err
+	}
+
+	if err := s.repo.MarkSent(ctx, rem.ID); err != nil {
+		s.logger.Error("failed to record sent reminder", "id", rem.ID, "error", err)
+		return true, err
+	}
+	return true, nil
+}
+
+// dueReminders returns the expiry and renewal dates of sub that fall within
+// [from, until]. Subscriptions renew monthly on the day they started.
+func dueReminders(sub models.Subscription, from, until time.Time) []due {
+	var result []due
+
+	if sub.EndDate != nil && !sub.EndDate.Before(from) && !sub.EndDate.After(until) {
+		result = append(result, due{kind: models.ReminderKindExpiry, date: *sub.EndDate})
+	}
+
+	renewal := nextRenewal(sub.StartDate, from)
+	if !renewal.After(until) && (sub.EndDate == nil || !renewal.After(*sub.EndDate)) {
+		result = append(result, due{kind: models.ReminderKindRenewal, date: renewal})
+	}
+
+	return result
+}
+
+// renewalDays returns the days of the month a subscription has to start on
+// to renew within [from, until]. A start day missing from the month of a
+// renewal overflows into the next one, as in nextRenewal: the 31st renews on
+// the 1st after a 30-day month.
+func renewalDays(from, until time.Time) []int {
+	seen := make(map[int]bool)
+	var days []int
+	for d := from; !d.After(until) && len(days) < 31; d = d.AddDate(0, 0, 1) {
+		candidates := []int{d.Day()}
+		// The day before the 1st is the last day of the previous month.
+		if overflow := d.AddDate(0, 0, -d.Day()).Day() + d.Day(); overflow <= 31 {
+			candidates = append(candidates, overflow)
+		}
+		for _, day := range candidates {
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
+
+func nextRenewal(start, from time.Time) time.Time {
+	months := (from.Year()-start.Year())*12 + int(from.Month()-start.Month())
+	if months < 1 {
+		months = 1
+	}
+
+	renewal := start.AddDate(0, months, 0)
+	for renewal.Before(from) {
+		months++
+		renewal = start.AddDate(0, months, 0)
+	}
+	return renewal
+}
+
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,77 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNextRenewal(t *testing.T) {
+	tests := []struct {
+		start, from, want time.Time
+	}{
+		{date(2025, 1, 1), date(2025, 3, 1), date(2025, 3, 1)},
+		{date(2025, 1, 1), date(2025, 3, 2), date(2025, 4, 1)},
+		{date(2025, 1, 1), date(2024, 12, 15), date(2025, 2, 1)},
+		{date(2024, 11, 1), date(2025, 1, 20), date(2025, 2, 1)},
+	}
+
+	for _, tt := range tests {
+		if got := nextRenewal(tt.start, tt.from); !got.Equal(tt.want) {
+			t.Errorf("nextRenewal(%s, %s) = %s, want %s", tt.start, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestDueReminders(t *testing.T) {
+	from := date(2025, 6, 28)
+	until := from.Add(72 * time.Hour)
+
+	end := date(2025, 7, 1)
+	sub := models.Subscription{StartDate: date(2025, 1, 1), EndDate: &end}
+
+	reminders := dueReminders(sub, from, until)
+	if len(reminders) != 2 {
+		t.Fatalf("Expected expiry and renewal reminders, got %v", reminders)
+	}
+	if reminders[0].kind != models.ReminderKindExpiry || reminders[1].kind != models.ReminderKindRenewal {
+		t.Errorf("Unexpected reminder kinds: %v", reminders)
+	}
+
+	ended := date(2025, 6, 1)
+	sub.EndDate = &ended
+	if reminders := dueReminders(sub, from, until); len(reminders) != 0 {
+		t.Errorf("Expected no reminders for ended subscription, got %v", reminders)
+	}
+}
+
+func TestRenewalDaysCoverDueRenewals(t *testing.T) {
+	for _, horizon := range []int{0, 2, 6, 30} {
+		for from := date(2024, 1, 1); from.Before(date(2025, 1, 1)); from = from.AddDate(0, 0, 1) {
+			until := from.AddDate(0, 0, horizon)
+			days := make(map[int]bool)
+			for _, day := range renewalDays(from, until) {
+				days[day] = true
+			}
+
+			if horizon < 30 && len(days) > horizon+4 {
+				t.Errorf("Expected at most %d days listed for %s..%s, got %d", horizon+4, from.Format("2006-01-02"), until.Format("2006-01-02"), len(days))
+			}
+
+			for start := date(2023, 10, 1); start.Before(date(2023, 11, 1)); start = start.AddDate(0, 0, 1) {
+				renews := false
+				for _, d := range dueReminders(models.Subscription{StartDate: start}, from, until) {
+					renews = renews || d.kind == models.ReminderKindRenewal
+				}
+				if renews && !days[start.Day()] {
+					t.Errorf("Expected day %d listed for %s..%s", start.Day(), from.Format("2006-01-02"), until.Format("2006-01-02"))
+				}
+			}
+		}
+	}
+}
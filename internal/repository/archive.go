@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// ErrArchiveRestored is returned by RestoreArchive for an archive that was
+// already restored.
+var ErrArchiveRestored = errors.New("archive already restored")
+
+// ArchiveStore writes the records of a new archive and returns its name.
+type ArchiveStore func(records []models.ArchivedSubscription) (string, error)
+
+// ArchiveTenants returns the tenants that have subscriptions ended before
+// endedBefore.
+func (r *SubscriptionRepository) ArchiveTenants(ctx context.Context, endedBefore time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM subscriptions WHERE end_date < $1 ORDER BY tenant_id`, endedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, id)
+	}
+
+	return tenants, rows.Err()
+}
+
+// Archive moves up to limit subscriptions of the tenant of ctx that ended
+// before endedBefore out of the subscriptions table, together with their
+// price changes. store is called with them before the transaction commits,
+// so they are only removed once their archive is written, and their prices
+// are added to the archived monthly totals; a file written for a
+// transaction that fails after all is left unreferenced. It returns nil when
+// there was nothing to archive.
+func (r *SubscriptionRepository) Archive(ctx context.Context, endedBefore time.Time, limit int, store ArchiveStore) (*models.Archive, error) {
+	tenantID := tenant.FromContext(ctx)
+	var archive *models.Archive
+	var archived []*models.Subscription
+
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		query := `
+			SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+			FROM subscriptions
+			WHERE tenant_id = $1 AND end_date < $2
+			ORDER BY end_date, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		`
+		rows, err := tx.QueryContext(ctx, query, tenantID, endedBefore, limit)
+		if err != nil {
+			return err
+		}
+		var records []models.ArchivedSubscription
+		for rows.Next() {
+			var rec models.ArchivedSubscription
+			s := &rec.Subscription
+			if err := rows.Scan(&s.ID, &s.ServiceName, &s.Price, &s.UserID, &s.StartDate, &s.EndDate, &s.CreatedAt, &s.UpdatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			s.TenantID = tenantID
+			records = append(records, rec)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(records) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(records))
+		index := make(map[uuid.UUID]int, len(records))
+		for i, rec := range records {
+			ids[i] = rec.ID
+			index[rec.ID] = i
+		}
+		changes, err := listPriceChanges(ctx, tx, tenantID, ids)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			i := index[c.SubscriptionID]
+			records[i].PriceChanges = append(records[i].PriceChanges, c)
+		}
+
+		name, err := store(records)
+		if err != nil {
+			return err
+		}
+
+		archive = &models.Archive{
+			ID:            uuid.New(),
+			Name:          name,
+			Subscriptions: len(records),
+			EndedBefore:   endedBefore,
+		}
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO archives (id, tenant_id, name, subscriptions, ended_before)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING created_at
+		`, archive.ID, tenantID, archive.Name, archive.Subscriptions, archive.EndedBefore).Scan(&archive.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := addArchivedTotals(ctx, tx, ids); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ANY($1::uuid[])`, uuidArray(ids)); err != nil {
+			return err
+		}
+
+		for i := range records {
+			archived = append(archived, &records[i].Subscription)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.written(ctx, archived...)
+	return archive, nil
+}
+
+// RestoreArchive puts the records of archive back into the subscriptions
+// table and takes their prices out of the archived monthly totals.
+// Subscriptions that exist again in the meantime are left as they are, but
+// their archived prices are taken out as well, since they are counted live.
+func (r *SubscriptionRepository) RestoreArchive(ctx context.Context, archive *models.Archive, records []models.ArchivedSubscription) error {
+	tenantID := tenant.FromContext(ctx)
+	var restored []*models.Subscription
+
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE archives SET restored_at = NOW()
+			WHERE id = $1 AND tenant_id = $2 AND restored_at IS NULL
+		`, archive.ID, tenantID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return ErrArchiveRestored
+		}
+
+		for i := range records {
+			sub := &records[i].Subscription
+			sub.TenantID = tenantID
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date, created_at, updated_at, tenant_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				ON CONFLICT (id) DO NOTHING
+			`, sub.ID, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, sub.CreatedAt, sub.UpdatedAt, tenantID)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected == 0 {
+				continue
+			}
+
+			for _, c := range records[i].PriceChanges {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO price_changes (id, subscription_id, effective_date, price, created_at, tenant_id)
+					VALUES ($1, $2, $3, $4, $5, $6)
+					ON CONFLICT DO NOTHING
+				`, c.ID, sub.ID, c.EffectiveDate, c.Price, c.CreatedAt, tenantID)
+				if err != nil {
+					return err
+				}
+			}
+			restored = append(restored, sub)
+		}
+
+		if err := subtractArchivedTotals(ctx, tx, tenantID, records); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM archived_totals WHERE tenant_id = $1 AND active_total = 0 AND started_total = 0`, tenantID)
+		return err
+	})
+	if err == nil {
+		r.written(ctx, restored...)
+	}
+	return err
+}
+
+func (r *SubscriptionRepository) GetArchive(ctx context.Context, id uuid.UUID) (*models.Archive, error) {
+	var a models.Archive
+	query := `SELECT id, name, subscriptions, ended_before, created_at, restored_at FROM archives WHERE id = $1 AND tenant_id = $2`
+	err := r.db.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(
+		&a.ID, &a.Name, &a.Subscriptions, &a.EndedBefore, &a.CreatedAt, &a.RestoredAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &a, err
+}
+
+func (r *SubscriptionRepository) ListArchives(ctx context.Context) ([]models.Archive, error) {
+	query := `SELECT id, name, subscriptions, ended_before, created_at, restored_at FROM archives WHERE tenant_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var archives []models.Archive
+	for rows.Next() {
+		var a models.Archive
+		if err := rows.Scan(&a.ID, &a.Name, &a.Subscriptions, &a.EndedBefore, &a.CreatedAt, &a.RestoredAt); err != nil {
+			return nil, err
+		}
+		archives = append(archives, a)
+	}
+
+	return archives, rows.Err()
+}
+
+// SetArchiveSize records the number of subscriptions left in an archive
+// after it was rewritten.
+func (r *SubscriptionRepository) SetArchiveSize(ctx context.Context, id uuid.UUID, subscriptions int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE archives SET subscriptions = $1 WHERE id = $2 AND tenant_id = $3`, subscriptions, id, tenant.FromContext(ctx))
+	return err
+}
+
+// addArchivedTotals adds the prices of the given subscriptions to the
+// archived monthly totals of every month they were active in, with the price
+// changes in effect in each month. The price changes must still be stored.
+func addArchivedTotals(ctx context.Context, tx *tracedTx, ids []uuid.UUID) error {
+	query := `
+		INSERT INTO archived_totals (tenant_id, user_id, service_name, month, active_total, started_total)
+		SELECT s.tenant_id, s.user_id, s.service_name, m.month::date,
+			SUM(` + priceIn("m.month::date") + `),
+			SUM(CASE WHEN m.month = date_trunc('month', s.start_date::timestamp) THEN ` + priceIn("m.month::date") + ` ELSE 0 END)
+		FROM subscriptions s
+		CROSS JOIN LATERAL generate_series(
+			date_trunc('month', s.start_date::timestamp),
+			date_trunc('month', s.end_date::timestamp),
+			interval '1 month'
+		) AS m(month)
+		WHERE s.id = ANY($1::uuid[])
+		GROUP BY s.tenant_id, s.user_id, s.service_name, m.month
+		ON CONFLICT (tenant_id, user_id, service_name, month) DO UPDATE
+		SET active_total = archived_totals.active_total + EXCLUDED.active_total,
+			started_total = archived_totals.started_total + EXCLUDED.started_total
+	`
+	_, err := tx.ExecContext(ctx, query, uuidArray(ids))
+	return err
+}
+
+// subtractArchivedTotals takes the prices of records out of the archived
+// monthly totals as addArchivedTotals added them, from the archived records
+// rather than the subscriptions table, whose rows may differ from them. The
+// price_changes CTE stands in for the table in priceIn.
+func subtractArchivedTotals(ctx context.Context, tx *tracedTx, tenantID string, records []models.ArchivedSubscription) error {
+	if len(records) == 0 {
+		return nil
+	}
+	subs := make([]*models.Subscription, len(records))
+	changes := []models.PriceChange{}
+	for i := range records {
+		subs[i] = &records[i].Subscription
+		for _, c := range records[i].PriceChanges {
+			c.SubscriptionID = records[i].ID
+			changes = append(changes, c)
+		}
+	}
+	subsJSON, err := json.Marshal(subs)
+	if err != nil {
+		return err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH archived AS (
+			SELECT * FROM jsonb_to_recordset($1::jsonb)
+				AS a(id uuid, user_id uuid, service_name text, price integer, start_date date, end_date date)
+		), price_changes AS (
+			SELECT * FROM jsonb_to_recordset($2::jsonb)
+				AS c(subscription_id uuid, effective_date date, price integer)
+		)
+		INSERT INTO archived_totals (tenant_id, user_id, service_name, month, active_total, started_total)
+		SELECT $3, s.user_id, s.service_name, m.month::date,
+			-SUM(` + priceIn("m.month::date") + `),
+			-SUM(CASE WHEN m.month = date_trunc('month', s.start_date::timestamp) THEN ` + priceIn("m.month::date") + ` ELSE 0 END)
+		FROM archived s
+		CROSS JOIN LATERAL generate_series(
+			date_trunc('month', s.start_date::timestamp),
+			date_trunc('month', s.end_date::timestamp),
+			interval '1 month'
+		) AS m(month)
+		GROUP BY s.user_id, s.service_name, m.month
+		ON CONFLICT (tenant_id, user_id, service_name, month) DO UPDATE
+		SET active_total = archived_totals.active_total + EXCLUDED.active_total,
+			started_total = archived_totals.started_total + EXCLUDED.started_total
+	`
+	_, err = tx.ExecContext(ctx, query, subsJSON, changesJSON, tenantID)
+	return err
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"testing"
+
+	"subscription-service/internal/models"
+)
+
+func TestCostConditions(t *testing.T) {
+	filter := &models.SubscriptionFilter{UserID: "u", ServiceName: "Netflix", StartMonth: "01-2024", EndMonth: "02-2024"}
+
+	live, args := costConditions(filter, "start_date", 2)
+	archived, _ := costConditions(filter, "month", 2)
+
+	if want := " AND user_id = $2 AND service_name = $3 AND start_date >= $4 AND start_date <= $5"; live != want {
+		t.Errorf("Expected %q, got %q", want, live)
+	}
+	if want := " AND user_id = $2 AND service_name = $3 AND month >= $4 AND month <= $5"; archived != want {
+		t.Errorf("Expected %q, got %q", want, archived)
+	}
+	if len(args) != 4 {
+		t.Errorf("Expected 4 arguments, got %d", len(args))
+	}
+}
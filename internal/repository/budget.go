@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// BudgetHorizonMonths is how far ahead, counting the current month, budget
+// alerts look at the projected spend.
+const BudgetHorizonMonths = 12
+
+type BudgetRepository struct {
+	db *tracedDB
+}
+
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{db: &tracedDB{db}}
+}
+
+// Upsert creates the budget of b's user and category or replaces its
+// services and amount, filling in the stored id and creation time.
+func (r *BudgetRepository) Upsert(ctx context.Context, b *models.Budget) error {
+	query := `
+		INSERT INTO budgets (id, user_id, category, services, amount, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, user_id, category) DO UPDATE
+		SET services = EXCLUDED.services, amount = EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, b.ID, b.UserID, b.Category, pq.Array(b.Services), b.Amount, b.CreatedAt, b.UpdatedAt, tenant.FromContext(ctx)).
+		Scan(&b.ID, &b.CreatedAt)
+}
+
+func (r *BudgetRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	return listBudgets(ctx, r.db, userID)
+}
+
+func (r *BudgetRepository) Delete(ctx context.Context, userID uuid.UUID, category string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM budgets WHERE user_id = $1 AND category = $2 AND tenant_id = $3`, userID, category, tenant.FromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// MonthlySpend sums, for every month in [from, to], the prices of the
+// subscriptions covered by b that are charged in that month by chargedIn,
+// as the monthly breakdown and statements do. Budgets follow that rule
+// rather than GetTotalCost's, which counts a subscription once, in the month
+// it starts.
+func (r *BudgetRepository) MonthlySpend(ctx context.Context, b *models.Budget, from, to time.Time) ([]models.MonthlyCost, error) {
+	services := make([]string, len(b.Services))
+	for i, s := range b.Services {
+		services[i] = strings.ToLower(s)
+	}
+
+	query := `
+		SELECT m.month, COALESCE(SUM(` + priceIn("m.month") + `), 0)
+		FROM generate_series($1::date, $2::date, interval '1 month') AS m(month)
+		LEFT JOIN subscriptions s
+			ON ` + chargedIn("m.month") + `
+			AND s.user_id = $3
+			AND ($4::text = '' OR lower(s.service_name) = ANY($5::text[]))
+			AND s.tenant_id = $6
+		GROUP BY m.month
+		ORDER BY m.month
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to, b.UserID, b.Category, pq.Array(services), tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []models.MonthlyCost
+	for rows.Next() {
+		var month time.Time
+		var total int
+		if err := rows.Scan(&month, &total); err != nil {
+			return nil, err
+		}
+		months = append(months, models.MonthlyCost{Month: month.Format("01-2006"), Total: total})
+	}
+
+	return months, rows.Err()
+}
+
+func listBudgets(ctx context.Context, db queryer, userID uuid.UUID) ([]models.Budget, error) {
+	query := `
+		SELECT id, user_id, category, services, amount, created_at, updated_at
+		FROM budgets
+		WHERE user_id = $1 AND tenant_id = $2
+		ORDER BY category
+	`
+	rows, err := db.QueryContext(ctx, query, userID, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []models.Budget
+	for rows.Next() {
+		var b models.Budget
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Category, pq.Array(&b.Services), &b.Amount, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	return budgets, rows.Err()
+}
+
+// insertBudgetAlerts runs write, which changes subscriptions of the given
+// users and returns them as written, and records a budget.exceeded event for
+// every budget of those users it pushes over its amount in at least one
+// month of the alert horizon. The spend is summed before and after write as
+// MonthlySpend does, so price changes count as they do in the budget status.
+// Months already over budget before the write do not alert again.
+func insertBudgetAlerts(ctx context.Context, tx *tracedTx, userIDs []uuid.UUID, write func() ([]*models.Subscription, error)) error {
+	from, to := budgetAlertHorizon(time.Now())
+	before, err := budgetSpend(ctx, tx, userIDs, from, to)
+	if err != nil {
+		return err
+	}
+	subs, err := write()
+	if err != nil {
+		return err
+	}
+	after, err := budgetSpend(ctx, tx, userIDs, from, to)
+	if err != nil {
+		return err
+	}
+	return insertEvents(ctx, tx, budgetAlerts(before, after, subs))
+}
+
+// budgetSpend sums, for every budget of the given users and every month in
+// [from, to], the prices of the subscriptions it covers that are active
+// during that month, as MonthlySpend does for one budget.
+func budgetSpend(ctx context.Context, db queryer, userIDs []uuid.UUID, from, to time.Time) ([]*budgetSpending, error) {
+	query := `
+		SELECT b.id, b.user_id, b.category, b.amount, m.month, COALESCE(SUM(` + priceIn("m.month") + `), 0)
+		FROM budgets b
+		CROSS JOIN generate_series($2::date, $3::date, interval '1 month') AS m(month)
+		LEFT JOIN subscriptions s
+			ON ` + chargedIn("m.month") + `
+			AND s.user_id = b.user_id
+			AND s.tenant_id = b.tenant_id
+			AND (b.category = '' OR lower(s.service_name) IN (SELECT lower(x) FROM unnest(b.services) AS x))
+		WHERE b.user_id = ANY($1::uuid[]) AND b.tenant_id = $4
+		GROUP BY b.id, m.month
+		ORDER BY b.id, m.month
+	`
+	rows, err := db.QueryContext(ctx, query, uuidArray(userIDs), from, to, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spending []*budgetSpending
+	for rows.Next() {
+		var b models.Budget
+		var month time.Time
+		var total int
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Category, &b.Amount, &month, &total); err != nil {
+			return nil, err
+		}
+		if len(spending) == 0 || spending[len(spending)-1].Budget.ID != b.ID {
+			spending = append(spending, &budgetSpending{Budget: b})
+		}
+		last := spending[len(spending)-1]
+		last.Months = append(last.Months, models.MonthlyCost{Month: month.Format("01-2006"), Total: total})
+	}
+
+	return spending, rows.Err()
+}
+
+type budgetSpending struct {
+	Budget models.Budget
+	Months []models.MonthlyCost
+}
+
+// budgetAlerts returns a budget.exceeded event for every budget that a write
+// of subs pushed over its amount in at least one month, going from the spend
+// before to the spend after. The alert names the first of subs of the
+// budget's user that is active in the first exceeded month.
+func budgetAlerts(before, after []*budgetSpending, subs []*models.Subscription) []outboxEvent {
+	previous := make(map[uuid.UUID][]models.MonthlyCost, len(before))
+	for _, spent := range before {
+		previous[spent.Budget.ID] = spent.Months
+	}
+
+	var events []outboxEvent
+	for _, spent := range after {
+		b := spent.Budget
+		prev, ok := previous[b.ID]
+		if !ok || len(prev) != len(spent.Months) {
+			continue
+		}
+		var exceeded []models.MonthlyCost
+		for i, m := range spent.Months {
+			if prev[i].Total <= b.Amount && m.Total > b.Amount {
+				exceeded = append(exceeded, m)
+			}
+		}
+		if len(exceeded) == 0 {
+			continue
+		}
+
+		month, _ := time.Parse("01-2006", exceeded[0].Month)
+		for _, sub := range subs {
+			if sub.UserID != b.UserID || !activeIn(sub, month) {
+				continue
+			}
+			alert := models.BudgetAlert{
+				UserID:         b.UserID,
+				SubscriptionID: sub.ID,
+				Category:       b.Category,
+				Amount:         b.Amount,
+				Months:         exceeded,
+			}
+			events = append(events, outboxEvent{Type: models.EventBudgetExceeded, SubscriptionID: sub.ID, UserID: b.UserID, Data: alert})
+			break
+		}
+	}
+	return events
+}
+
+// budgetAlertHorizon is the range of months budget alerts look at, starting
+// with the current month.
+func budgetAlertHorizon(now time.Time) (time.Time, time.Time) {
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, BudgetHorizonMonths-1, 0)
+}
+
+func activeIn(sub *models.Subscription, month time.Time) bool {
+	m := monthIndex(month)
+	return monthIndex(sub.StartDate) <= m && (sub.EndDate == nil || monthIndex(*sub.EndDate) >= m)
+}
+
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
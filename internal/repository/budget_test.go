@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestActiveIn(t *testing.T) {
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{StartDate: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), EndDate: &end}
+
+	for month, want := range map[int]bool{1: false, 2: true, 6: true, 7: false} {
+		if got := activeIn(sub, time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC)); got != want {
+			t.Errorf("Expected activeIn(%d) = %v, got %v", month, want, got)
+		}
+	}
+}
+
+func TestBudgetAlerts(t *testing.T) {
+	userID := uuid.New()
+	budget := models.Budget{ID: uuid.New(), UserID: userID, Amount: 1000}
+	months := func(totals ...int) []models.MonthlyCost {
+		costs := make([]models.MonthlyCost, len(totals))
+		for i, total := range totals {
+			costs[i] = models.MonthlyCost{Month: time.Date(2025, time.Month(3+i), 1, 0, 0, 0, 0, time.UTC).Format("01-2006"), Total: total}
+		}
+		return costs
+	}
+	before := []*budgetSpending{{Budget: budget, Months: months(1200, 900, 900)}}
+	after := []*budgetSpending{{Budget: budget, Months: months(1300, 1100, 900)}}
+
+	ended := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*models.Subscription{
+		{ID: uuid.New(), UserID: userID, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: &ended},
+		{ID: uuid.New(), UserID: userID, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	events := budgetAlerts(before, after, subs)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(events))
+	}
+	alert := events[0].Data.(models.BudgetAlert)
+	if len(alert.Months) != 1 || alert.Months[0].Month != "04-2025" {
+		t.Errorf("Expected only 04-2025 to be newly exceeded, got %+v", alert.Months)
+	}
+	if alert.SubscriptionID != subs[1].ID {
+		t.Errorf("Expected the alert to name the subscription active in 04-2025, got %s", alert.SubscriptionID)
+	}
+}
@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// errBulkChanged rolls back a bulk operation whose subscriptions changed
+// since its preview.
+var errBulkChanged = errors.New("bulk operation changed")
+
+// BulkMatch describes the subscriptions a bulk operation would apply to.
+type BulkMatch struct {
+	Matched int
+	Sample  []models.Subscription
+
+	// Hash identifies the set of matching subscriptions.
+	Hash string
+
+	// EndBeforeStart counts the matching subscriptions that start after the
+	// end date a bulk update would set.
+	EndBeforeStart int
+
+	// Overlaps counts the pairs of subscriptions of the same user and service
+	// that the end date a bulk update would set makes overlap, and
+	// OverlapSample lists the first sampleSize of them.
+	Overlaps      int
+	OverlapSample []models.SubscriptionOverlap
+}
+
+// BulkExecution is the outcome of ExecuteBulkOperation. When the
+// subscriptions matching the operation changed since its preview, Changed
+// says how and nothing was affected.
+type BulkExecution struct {
+	Operation *models.BulkOperation
+	Affected  int
+	Changed   string
+}
+
+// MatchBulk counts the subscriptions of the tenant of ctx matching the filter
+// of op on the primary and returns the first sampleSize of them, along with
+// the overlaps an update of their end date would create.
+func (r *SubscriptionRepository) MatchBulk(ctx context.Context, op *models.BulkOperation, sampleSize int) (*BulkMatch, error) {
+	tenantID := tenant.FromContext(ctx)
+	filter := &op.Filter
+
+	match, _, err := matchSummary(ctx, r.db, tenantID, filter, op.EndDate, false)
+	if err != nil {
+		return nil, err
+	}
+	if op.SetEndDate {
+		match.OverlapSample, match.Overlaps, err = bulkOverlaps(ctx, r.db, tenantID, filter, op.EndDate, sampleSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conds, args := matchConditions(filter, 2)
+	query := `
+		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		FROM subscriptions
+		WHERE tenant_id = $1` + conds + fmt.Sprintf(`
+		ORDER BY start_date, id
+		LIMIT $%d`, len(args)+2)
+	args = append([]interface{}{tenantID}, args...)
+	rows, err := r.db.QueryContext(ctx, query, append(args, sampleSize)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub := models.Subscription{TenantID: tenantID}
+		if err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, err
+		}
+		match.Sample = append(match.Sample, sub)
+	}
+
+	return match, rows.Err()
+}
+
+// CreateBulkOperation stores op with the hash of the subscriptions it
+// matched until it is executed or expires.
+func (r *SubscriptionRepository) CreateBulkOperation(ctx context.Context, op *models.BulkOperation, matchedHash string) error {
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO bulk_operations (id, tenant_id, operation, matched, matched_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.db.ExecContext(ctx, query, op.ID, tenant.FromContext(ctx), payload, op.Matched, matchedHash, op.ExpiresAt)
+	return err
+}
+
+// ExecuteBulkOperation applies the bulk operation id in one transaction, with
+// one statement for the subscriptions and one for their events. It returns
+// nil for an operation that is unknown, expired or already executed. The
+// operation is used up only when exactly the subscriptions of its preview
+// still match, and an update does not end any of them before it starts nor,
+// unless the operation allows it, make any of them overlap.
+func (r *SubscriptionRepository) ExecuteBulkOperation(ctx context.Context, id uuid.UUID) (*BulkExecution, error) {
+	tenantID := tenant.FromContext(ctx)
+	exec := &BulkExecution{Operation: &models.BulkOperation{ID: id}}
+	op := exec.Operation
+	var affected []*models.Subscription
+
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		var payload []byte
+		var matchedHash string
+		err := tx.QueryRowContext(ctx, `
+			UPDATE bulk_operations SET executed_at = NOW()
+			WHERE id = $1 AND tenant_id = $2 AND executed_at IS NULL AND expires_at > NOW()
+			RETURNING operation, matched, matched_hash
+		`, id, tenantID).Scan(&payload, &op.Matched, &matchedHash)
+		if err == sql.ErrNoRows {
+			exec = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(payload, op); err != nil {
+			return err
+		}
+
+		var endDate *time.Time
+		if op.SetEndDate {
+			endDate = op.EndDate
+		}
+		match, users, err := matchSummary(ctx, tx, tenantID, &op.Filter, endDate, true)
+		if err != nil {
+			return err
+		}
+		switch {
+		case match.Hash != matchedHash:
+			exec.Changed = fmt.Sprintf("%d subscriptions previewed, %d other ones match now", op.Matched, match.Matched)
+			return errBulkChanged
+		case match.EndBeforeStart > 0:
+			exec.Changed = fmt.Sprintf("%d subscriptions would end before they start", match.EndBeforeStart)
+			return errBulkChanged
+		}
+		if op.SetEndDate && !op.AllowOverlap {
+			_, overlaps, err := bulkOverlaps(ctx, tx, tenantID, &op.Filter, op.EndDate, 0)
+			if err != nil {
+				return err
+			}
+			if overlaps > 0 {
+				exec.Changed = fmt.Sprintf("%d pairs of subscriptions would overlap", overlaps)
+				return errBulkChanged
+			}
+		}
+
+		if op.Action == models.BulkActionDelete {
+			affected, err = bulkDelete(ctx, tx, tenantID, op)
+			if err != nil {
+				return err
+			}
+			return insertEvents(ctx, tx, subscriptionEvents(models.EventSubscriptionDeleted, affected))
+		}
+
+		return insertBudgetAlerts(ctx, tx, users, func() ([]*models.Subscription, error) {
+			affected, err = bulkUpdate(ctx, tx, tenantID, op, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			return affected, insertEvents(ctx, tx, subscriptionEvents(models.EventSubscriptionUpdated, affected))
+		})
+	})
+	if errors.Is(err, errBulkChanged) {
+		return exec, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if exec != nil {
+		exec.Affected = len(affected)
+		r.written(ctx, affected...)
+	}
+	return exec, nil
+}
+
+// matchSummary counts and hashes the subscriptions of tenantID matching
+// filter, counting those that start after endDate, and lists their users.
+// With lock, the subscriptions are locked until the end of the transaction.
+func matchSummary(ctx context.Context, db rowQueryer, tenantID string, filter *models.SubscriptionFilter, endDate *time.Time, lock bool) (*BulkMatch, []uuid.UUID, error) {
+	conds, args := matchConditions(filter, 3)
+	inner := `SELECT id, user_id, start_date FROM subscriptions WHERE tenant_id = $1` + conds
+	if lock {
+		inner += ` FOR UPDATE`
+	}
+	query := `
+		SELECT COUNT(*),
+			md5(COALESCE(string_agg(id::text, ',' ORDER BY id), '')),
+			COUNT(*) FILTER (WHERE $2::date IS NOT NULL AND start_date > $2::date),
+			COALESCE(array_agg(DISTINCT user_id), '{}')::text[]
+		FROM (` + inner + `) m`
+
+	match := &BulkMatch{}
+	var userIDs pq.StringArray
+	err := db.QueryRowContext(ctx, query, append([]interface{}{tenantID, endDate}, args...)...).
+		Scan(&match.Matched, &match.Hash, &match.EndBeforeStart, &userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make([]uuid.UUID, len(userIDs))
+	for i, id := range userIDs {
+		if users[i], err = uuid.Parse(id); err != nil {
+			return nil, nil, err
+		}
+	}
+	return match, users, nil
+}
+
+// bulkOverlaps returns the first limit pairs of subscriptions of tenantID of
+// the same user and service that overlap once the subscriptions matching
+// filter end on endDate (nil for open ended) but did not before, with their
+// number. Pairs are reported as the subscriptions are now, as ListOverlaps
+// does.
+func bulkOverlaps(ctx context.Context, db queryer, tenantID string, filter *models.SubscriptionFilter, endDate *time.Time, limit int) ([]models.SubscriptionOverlap, int, error) {
+	conds, args := matchConditions(filter, 4)
+	query := `
+		WITH matched AS (
+			SELECT id, user_id, lower(service_name) AS service FROM subscriptions WHERE tenant_id = $1` + conds + `
+		), periods AS (
+			SELECT s.*, m.id IS NOT NULL AS matched,
+				CASE WHEN m.id IS NOT NULL THEN $2::date ELSE s.end_date END AS new_end_date
+			FROM subscriptions s
+			LEFT JOIN matched m ON m.id = s.id
+			WHERE s.tenant_id = $1
+				AND (s.user_id, lower(s.service_name)) IN (SELECT user_id, service FROM matched)
+		)
+		SELECT a.id, a.service_name, a.price, a.user_id, a.start_date, a.end_date, a.created_at, a.updated_at,
+			b.id, b.service_name, b.price, b.user_id, b.start_date, b.end_date, b.created_at, b.updated_at,
+			COUNT(*) OVER ()
+		FROM periods a
+		JOIN periods b
+			ON b.user_id = a.user_id
+			AND lower(b.service_name) = lower(a.service_name)
+			AND b.id > a.id
+		WHERE (a.matched OR b.matched)
+			AND b.start_date <= COALESCE(a.new_end_date, 'infinity'::date)
+			AND a.start_date <= COALESCE(b.new_end_date, 'infinity'::date)
+			AND NOT (b.start_date <= COALESCE(a.end_date, 'infinity'::date)
+				AND a.start_date <= COALESCE(b.end_date, 'infinity'::date))
+		ORDER BY a.user_id, lower(a.service_name), a.start_date, b.start_date
+		LIMIT GREATEST($3, 1)`
+	rows, err := db.QueryContext(ctx, query, append([]interface{}{tenantID, endDate, limit}, args...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var overlaps []models.SubscriptionOverlap
+	var total int
+	for rows.Next() {
+		var o models.SubscriptionOverlap
+		a, b := &o.First, &o.Second
+		err := rows.Scan(
+			&a.ID, &a.ServiceName, &a.Price, &a.UserID, &a.StartDate, &a.EndDate, &a.CreatedAt, &a.UpdatedAt,
+			&b.ID, &b.ServiceName, &b.Price, &b.UserID, &b.StartDate, &b.EndDate, &b.CreatedAt, &b.UpdatedAt,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		a.TenantID, b.TenantID = tenantID, tenantID
+		if len(overlaps) < limit {
+			overlaps = append(overlaps, o)
+		}
+	}
+	return overlaps, total, rows.Err()
+}
+
+// bulkDelete deletes the subscriptions matching op and returns them.
+func bulkDelete(ctx context.Context, tx *tracedTx, tenantID string, op *models.BulkOperation) ([]*models.Subscription, error) {
+	conds, args := matchConditions(&op.Filter, 2)
+	query := `
+		DELETE FROM subscriptions
+		WHERE tenant_id = $1` + conds + `
+		RETURNING id, service_name, price, user_id, start_date, end_date, created_at, updated_at`
+	return scanBulk(ctx, tx, tenantID, query, append([]interface{}{tenantID}, args...)...)
+}
+
+// bulkUpdate applies the update of op to the subscriptions matching it and
+// returns them as updated.
+func bulkUpdate(ctx context.Context, tx *tracedTx, tenantID string, op *models.BulkOperation, now time.Time) ([]*models.Subscription, error) {
+	conds, args := matchConditions(&op.Filter, 6)
+	query := `
+		UPDATE subscriptions
+		SET price = COALESCE($2::integer, price),
+			end_date = CASE WHEN $3 THEN $4::date ELSE end_date END,
+			updated_at = $5
+		WHERE tenant_id = $1` + conds + `
+		RETURNING id, service_name, price, user_id, start_date, end_date, created_at, updated_at`
+	args = append([]interface{}{tenantID, op.Price, op.SetEndDate, op.EndDate, now}, args...)
+	return scanBulk(ctx, tx, tenantID, query, args...)
+}
+
+func scanBulk(ctx context.Context, tx *tracedTx, tenantID, query string, args ...interface{}) ([]*models.Subscription, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*models.Subscription
+	for rows.Next() {
+		sub := &models.Subscription{TenantID: tenantID}
+		if err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func subscriptionEvents(eventType string, subs []*models.Subscription) []outboxEvent {
+	events := make([]outboxEvent, len(subs))
+	for i, sub := range subs {
+		events[i] = outboxEvent{Type: eventType, SubscriptionID: sub.ID, UserID: sub.UserID, Data: sub}
+	}
+	return events
+}
+
+// matchConditions renders the conditions of filter that bulk operations
+// apply, numbering arguments from n.
+func matchConditions(filter *models.SubscriptionFilter, n int) (string, []interface{}) {
+	conds, args := costConditions(filter, "start_date", n)
+	if filter.Query != "" {
+		n += len(args)
+		conds += " AND " + serviceNameMatch(n)
+		args = append(args, strings.ToLower(filter.Query), escapeLike(strings.ToLower(filter.Query)))
+	}
+	return conds, args
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"subscription-service/internal/models"
+)
+
+func TestMatchConditionsNumbering(t *testing.T) {
+	conds, args := matchConditions(&models.SubscriptionFilter{UserID: "u", StartMonth: "01-2025", Query: "net"}, 3)
+	if len(args) != 4 {
+		t.Fatalf("Expected 4 arguments, got %d", len(args))
+	}
+	for _, placeholder := range []string{"user_id = $3", "start_date >= $4", "$5::text", "$6::text"} {
+		if !strings.Contains(conds, placeholder) {
+			t.Errorf("Expected %q in conditions: %s", placeholder, conds)
+		}
+	}
+}
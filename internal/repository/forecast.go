@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// CreatePriceChange schedules change, replacing the price change of the same
+// subscription and month if there is one. Monthly totals and budgets count
+// price changes, so the subscription is reported as written and budgets it
+// pushes over their amount alert.
+func (r *SubscriptionRepository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	query := `
+		INSERT INTO price_changes (id, subscription_id, effective_date, price, created_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (subscription_id, effective_date) DO UPDATE SET price = EXCLUDED.price
+		RETURNING id, created_at
+	`
+	var sub *models.Subscription
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		var err error
+		if sub, err = lockSubscription(ctx, tx, change.SubscriptionID); err != nil {
+			return err
+		}
+		return insertBudgetAlerts(ctx, tx, []uuid.UUID{sub.UserID}, func() ([]*models.Subscription, error) {
+			err := tx.QueryRowContext(ctx, query, change.ID, change.SubscriptionID, change.EffectiveDate, change.Price, change.CreatedAt, sub.TenantID).
+				Scan(&change.ID, &change.CreatedAt)
+			return []*models.Subscription{sub}, err
+		})
+	})
+	if err != nil {
+		return err
+	}
+	r.written(ctx, sub)
+	return nil
+}
+
+// DeletePriceChange removes the price change id of a subscription, alerting
+// like CreatePriceChange for budgets the price it falls back to exceeds.
+func (r *SubscriptionRepository) DeletePriceChange(ctx context.Context, subscriptionID, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM price_changes WHERE id = $1 AND subscription_id = $2 AND tenant_id = $3`
+	var sub *models.Subscription
+	deleted := false
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		var err error
+		sub, err = lockSubscription(ctx, tx, subscriptionID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return insertBudgetAlerts(ctx, tx, []uuid.UUID{sub.UserID}, func() ([]*models.Subscription, error) {
+			result, err := tx.ExecContext(ctx, query, id, subscriptionID, sub.TenantID)
+			if err != nil {
+				return nil, err
+			}
+			affected, err := result.RowsAffected()
+			deleted = affected > 0
+			return []*models.Subscription{sub}, err
+		})
+	})
+	if err != nil || !deleted {
+		return false, err
+	}
+	r.written(ctx, sub)
+	return true, nil
+}
+
+// lockSubscription returns the subscription id of the tenant of ctx, locked
+// until the end of the transaction, or sql.ErrNoRows.
+func lockSubscription(ctx context.Context, tx *tracedTx, id uuid.UUID) (*models.Subscription, error) {
+	sub := &models.Subscription{TenantID: tenant.FromContext(ctx)}
+	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, id, sub.TenantID).Scan(
+		&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListPriceChanges returns the price changes of the given subscriptions,
+// ordered by subscription and effective date.
+func (r *SubscriptionRepository) ListPriceChanges(ctx context.Context, subscriptionIDs []uuid.UUID) ([]models.PriceChange, error) {
+	return listPriceChanges(ctx, r.db, tenant.FromContext(ctx), subscriptionIDs)
+}
+
+func listPriceChanges(ctx context.Context, db queryer, tenantID string, subscriptionIDs []uuid.UUID) ([]models.PriceChange, error) {
+	query := `
+		SELECT id, subscription_id, effective_date, price, created_at
+		FROM price_changes
+		WHERE subscription_id = ANY($1::uuid[]) AND tenant_id = $2
+		ORDER BY subscription_id, effective_date
+	`
+	rows, err := db.QueryContext(ctx, query, uuidArray(subscriptionIDs), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.PriceChange
+	for rows.Next() {
+		var c models.PriceChange
+		if err := rows.Scan(&c.ID, &c.SubscriptionID, &c.EffectiveDate, &c.Price, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// ListActiveSince returns the subscriptions matching filter that are still
+// running in or after the month from.
+func (r *SubscriptionRepository) ListActiveSince(ctx context.Context, filter *models.SubscriptionFilter, from time.Time) ([]models.Subscription, error) {
+	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE (end_date IS NULL OR end_date >= $1) AND tenant_id = $2`
+	args := []interface{}{from, tenant.FromContext(ctx)}
+	argCount := 3
+
+	if filter.UserID != "" {
+		query += fmt.Sprintf(" AND user_id = $%d", argCount)
+		args = append(args, filter.UserID)
+		argCount++
+	}
+
+	if filter.ServiceName != "" {
+		query += fmt.Sprintf(" AND service_name = $%d", argCount)
+		args = append(args, filter.ServiceName)
+		argCount++
+	}
+
+	query += " ORDER BY start_date, id"
+
+	rows, err := r.replicas.reader(ctx, r.db, filterKey(tenant.FromContext(ctx), filter)).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		var sub models.Subscription
+		if err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sub.TenantID = tenant.FromContext(ctx)
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, rows.Err()
+}
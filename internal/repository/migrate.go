@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"subscription-service/internal/config"
+	"subscription-service/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+type MigrationStatus struct {
+	Version uint
+	Latest  uint
+	Dirty   bool
+}
+
+func (s MigrationStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// Migrator applies the migrations embedded in the binary.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+func NewMigrator(cfg *config.Config) (*Migrator, error) {
+	db, err := sql.Open("postgres", cfg.GetDBConnString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init migration driver: %w", err)
+	}
+
+	src, err := openMigrationSource()
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("failed to init migrations: %w", err)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down rolls back the given number of migrations.
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return errors.New("steps must be at least 1")
+	}
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the schema version without running migrations and clears the
+// dirty flag. A version of -1 means no migrations are applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, err
+	}
+
+	return MigrationStatus{Version: version, Latest: latest, Dirty: dirty}, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func RunMigrations(cfg *config.Config) error {
+	m, err := NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return m.Up()
+}
+
+func LatestMigrationVersion() (uint, error) {
+	src, err := openMigrationSource()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+func MigrationVersion(ctx context.Context, db *sql.DB) (uint, bool, error) {
+	var version uint
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// MigrationCheck reports an error unless the schema is clean and at the
+// latest version shipped with the binary.
+func MigrationCheck(db *sql.DB) (func(context.Context) error, error) {
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		version, dirty, err := MigrationVersion(ctx, db)
+		if err != nil {
+			return fmt.Errorf("failed to read migration version: %w", err)
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != latest {
+			return fmt.Errorf("schema version %d, expected %d", version, latest)
+		}
+		return nil
+	}, nil
+}
+
+func openMigrationSource() (source.Driver, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	return src, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
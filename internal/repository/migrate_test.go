@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"subscription-service/migrations"
+)
+
+func TestEmbeddedMigrationsHaveDownFiles(t *testing.T) {
+	ups, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ups) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for _, up := range ups {
+		down := strings.TrimSuffix(up, ".up.sql") + ".down.sql"
+		content, err := fs.ReadFile(migrations.FS, down)
+		if err != nil {
+			t.Errorf("Missing down migration for %s", up)
+			continue
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			t.Errorf("Down migration %s is empty", down)
+		}
+	}
+
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		t.Fatalf("Failed to read latest migration version: %v", err)
+	}
+	if latest == 0 {
+		t.Error("Expected latest migration version to be set")
+	}
+}
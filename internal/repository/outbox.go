@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertEvent(ctx context.Context, db execer, eventType string, sub *models.Subscription) error {
+	return insertEventPayload(ctx, db, eventType, sub.ID, sub.UserID, sub)
+}
+
+func insertEventPayload(ctx context.Context, db execer, eventType string, subscriptionID, userID uuid.UUID, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event payload: %w", err)
+	}
+
+	query := `INSERT INTO outbox_events (event_type, subscription_id, user_id, payload, tenant_id) VALUES ($1, $2, $3, $4, $5)`
+	_, err = db.ExecContext(ctx, query, eventType, subscriptionID, userID, payload, tenant.FromContext(ctx))
+	return err
+}
+
+// outboxEvent is an event to store with insertEvents.
+type outboxEvent struct {
+	Type           string
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Data           any
+}
+
+// insertEvents stores events in one statement, in order.
+func insertEvents(ctx context.Context, db execer, events []outboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	types := make(pq.StringArray, len(events))
+	subscriptionIDs := make([]uuid.UUID, len(events))
+	userIDs := make([]uuid.UUID, len(events))
+	payloads := make(pq.StringArray, len(events))
+	for i, e := range events {
+		payload, err := json.Marshal(e.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode event payload: %w", err)
+		}
+		types[i], subscriptionIDs[i], userIDs[i], payloads[i] = e.Type, e.SubscriptionID, e.UserID, string(payload)
+	}
+
+	query := `
+		INSERT INTO outbox_events (event_type, subscription_id, user_id, payload, tenant_id)
+		SELECT e.event_type, e.subscription_id, e.user_id, e.payload, $5
+		FROM unnest($1::text[], $2::uuid[], $3::uuid[], $4::jsonb[]) WITH ORDINALITY
+			AS e(event_type, subscription_id, user_id, payload, n)
+		ORDER BY e.n
+	`
+	_, err := db.ExecContext(ctx, query, types, uuidArray(subscriptionIDs), uuidArray(userIDs), payloads, tenant.FromContext(ctx))
+	return err
+}
+
+// withTx runs fn in a transaction that is committed when fn succeeds and
+// rolled back otherwise.
+func withTx(ctx context.Context, db *tracedDB, fn func(tx *tracedTx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type EventRepository struct {
+	db *tracedDB
+}
+
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{db: &tracedDB{db}}
+}
+
+// Horizon returns the oldest transaction still running. Every event stored
+// by an older transaction is committed or gone, and no event stored later
+// can order before it.
+func (r *EventRepository) Horizon(ctx context.Context) (uint64, error) {
+	var horizon uint64
+	err := r.db.QueryRowContext(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())`).Scan(&horizon)
+	return horizon, err
+}
+
+// ListCommitted returns up to limit events that follow after in (txid, id)
+// order and were stored by transactions older than horizon, optionally
+// restricted to one tenant (all when tenantID is empty) and one user.
+func (r *EventRepository) ListCommitted(ctx context.Context, after models.Event, horizon uint64, tenantID string, userID *uuid.UUID, limit int) ([]models.Event, error) {
+	query := `
+		SELECT id, event_type, subscription_id, user_id, payload, created_at, tenant_id, txid
+		FROM outbox_events
+		WHERE (txid, id) > ($1::xid8, $2) AND txid < $3::xid8
+		  AND ($4::uuid IS NULL OR user_id = $4) AND ($5 = '' OR tenant_id = $5)
+		ORDER BY txid, id
+		LIMIT $6
+	`
+	return r.list(ctx, query, after.TxID, after.ID, horizon, userID, tenantID, limit)
+}
+
+// ListAfter returns up to limit events of a tenant stored by transactions
+// older than horizon that follow the event afterID in (txid, id) order,
+// optionally restricted to one user. When afterID is no longer stored, the
+// events with a greater id are returned.
+func (r *EventRepository) ListAfter(ctx context.Context, afterID int64, horizon uint64, tenantID string, userID *uuid.UUID, limit int) ([]models.Event, error) {
+	query := `
+		SELECT e.id, e.event_type, e.subscription_id, e.user_id, e.payload, e.created_at, e.tenant_id, e.txid
+		FROM outbox_events e
+		LEFT JOIN outbox_events c ON c.id = $1 AND c.tenant_id = $3
+		WHERE (CASE WHEN c.id IS NULL THEN e.id > $1 ELSE (e.txid, e.id) > (c.txid, c.id) END)
+		  AND e.txid < $2::xid8 AND e.tenant_id = $3 AND ($4::uuid IS NULL OR e.user_id = $4)
+		ORDER BY e.txid, e.id
+		LIMIT $5
+	`
+	return r.list(ctx, query, afterID, horizon, tenantID, userID, limit)
+}
+
+func (r *EventRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Event, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.SubscriptionID, &e.UserID, &e.Payload, &e.CreatedAt, &e.TenantID, &e.TxID); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+)
+
+// OverlapError is returned by CreateExclusive and UpdateExclusive when the
+// user already pays for the same service during part of the period.
+type OverlapError struct {
+	Conflicts []models.Subscription
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("subscription overlaps %d existing subscription(s) to the same service", len(e.Conflicts))
+}
+
+// CreateExclusive is Create that fails with an *OverlapError instead of
+// inserting a subscription overlapping another one of the same user and
+// service.
+func (r *SubscriptionRepository) CreateExclusive(ctx context.Context, sub *models.Subscription) error {
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		if err := checkOverlap(ctx, tx, sub); err != nil {
+			return err
+		}
+		return insertSubscription(ctx, tx, sub)
+	})
+	if err == nil {
+		r.written(ctx, sub)
+	}
+	return err
+}
+
+// UpdateExclusive is Update that fails with an *OverlapError instead of
+// moving a subscription onto the period of another one.
+func (r *SubscriptionRepository) UpdateExclusive(ctx context.Context, sub *models.Subscription) error {
+	var old *models.Subscription
+	err := withTx(ctx, r.db, func(tx *tracedTx) (err error) {
+		if err := checkOverlap(ctx, tx, sub); err != nil {
+			return err
+		}
+		old, err = updateSubscription(ctx, tx, sub)
+		return err
+	})
+	if err == nil && old != nil {
+		r.written(ctx, old, sub)
+	}
+	return err
+}
+
+// checkOverlap serializes writers of the same tenant, user and service with a
+// transaction-scoped advisory lock, so that two concurrent requests cannot
+// both pass the check, then looks for subscriptions intersecting sub's
+// period. A missing end date means the subscription is still running.
+func checkOverlap(ctx context.Context, tx *tracedTx, sub *models.Subscription) error {
+	tenantID := tenant.FromContext(ctx)
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($3 || '/' || $1 || '/' || lower($2)))`, sub.UserID.String(), sub.ServiceName, tenantID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		FROM subscriptions
+		WHERE user_id = $1 AND lower(service_name) = lower($2) AND id <> $3
+			AND ($4::date IS NULL OR start_date <= $4)
+			AND (end_date IS NULL OR end_date >= $5)
+			AND tenant_id = $6
+		ORDER BY start_date
+	`
+	rows, err := tx.QueryContext(ctx, query, sub.UserID, sub.ServiceName, sub.ID, sub.EndDate, sub.StartDate, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var conflicts []models.Subscription
+	for rows.Next() {
+		var s models.Subscription
+		if err := rows.Scan(&s.ID, &s.ServiceName, &s.Price, &s.UserID, &s.StartDate, &s.EndDate, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return err
+		}
+		s.TenantID = tenantID
+		conflicts = append(conflicts, s)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return &OverlapError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// ListOverlaps returns every pair of subscriptions of the same user and
+// service whose periods intersect, each pair once.
+func (r *SubscriptionRepository) ListOverlaps(ctx context.Context, filter *models.SubscriptionFilter) ([]models.SubscriptionOverlap, error) {
+	query := `
+		SELECT a.id, a.service_name, a.price, a.user_id, a.start_date, a.end_date, a.created_at, a.updated_at,
+			b.id, b.service_name, b.price, b.user_id, b.start_date, b.end_date, b.created_at, b.updated_at
+		FROM subscriptions a
+		JOIN subscriptions b
+			ON b.tenant_id = a.tenant_id
+			AND b.user_id = a.user_id
+			AND lower(b.service_name) = lower(a.service_name)
+			AND b.id > a.id
+			AND b.start_date <= COALESCE(a.end_date, 'infinity'::date)
+			AND a.start_date <= COALESCE(b.end_date, 'infinity'::date)
+		WHERE a.tenant_id = $1`
+	args := []interface{}{tenant.FromContext(ctx)}
+	argCount := 2
+
+	if filter.UserID != "" {
+		query += fmt.Sprintf(" AND a.user_id = $%d", argCount)
+		args = append(args, filter.UserID)
+		argCount++
+	}
+
+	if filter.ServiceName != "" {
+		query += fmt.Sprintf(" AND lower(a.service_name) = lower($%d)", argCount)
+		args = append(args, filter.ServiceName)
+		argCount++
+	}
+
+	query += " ORDER BY a.user_id, lower(a.service_name), a.start_date, b.start_date"
+
+	rows, err := r.replicas.reader(ctx, r.db, filterKey(tenant.FromContext(ctx), filter)).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overlaps []models.SubscriptionOverlap
+	for rows.Next() {
+		var o models.SubscriptionOverlap
+		a, b := &o.First, &o.Second
+		err := rows.Scan(
+			&a.ID, &a.ServiceName, &a.Price, &a.UserID, &a.StartDate, &a.EndDate, &a.CreatedAt, &a.UpdatedAt,
+			&b.ID, &b.ServiceName, &b.Price, &b.UserID, &b.StartDate, &b.EndDate, &b.CreatedAt, &b.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		overlaps = append(overlaps, o)
+	}
+
+	return overlaps, rows.Err()
+}
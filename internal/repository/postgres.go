@@ -1,33 +1,39 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"subscription-service/internal/config"
 	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type SubscriptionRepository struct {
-	db *sql.DB
+	db       *tracedDB
+	replicas *ReplicaSet
+	onWrite  []WriteHook
 }
 
+// WriteHook is called after a write commits with the affected subscriptions,
+// both as they were before and after an update.
+type WriteHook func(ctx context.Context, affected []models.Subscription)
+
 func NewPostgresDB(cfg *config.Config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.GetDBConnString())
+	db, err := openDB(cfg.GetDBConnString(), cfg.Tenancy.RowLevelSecurity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
@@ -36,79 +42,124 @@ func NewPostgresDB(cfg *config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
-func RunMigrations(cfg *config.Config) error {
-	db, err := sql.Open("postgres", cfg.GetDBConnString())
+// NewWorkerDB opens the pool of the background workers that act for every
+// tenant when row-level security is enabled. It connects through the worker
+// DSN, as a role the policies do not apply to.
+func NewWorkerDB(cfg *config.Config) (*sql.DB, error) {
+	db, err := openDB(cfg.Tenancy.WorkerDSN, false)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to open worker database: %w", err)
 	}
-	defer db.Close()
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return err
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping worker database: %w", err)
 	}
+	return db, nil
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return err
-	}
+func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: &tracedDB{db}}
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
-	}
+// OnWrite registers fn to be called after every committed write. Hooks run
+// synchronously and must not fail the write.
+func (r *SubscriptionRepository) OnWrite(fn WriteHook) {
+	r.onWrite = append(r.onWrite, fn)
+}
 
-	return nil
+// UseReplicas sends reads to the replicas of rs from now on.
+func (r *SubscriptionRepository) UseReplicas(rs *ReplicaSet) {
+	r.replicas = rs
 }
 
-func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
-	return &SubscriptionRepository{db: db}
+func (r *SubscriptionRepository) written(ctx context.Context, affected ...*models.Subscription) {
+	subs := make([]models.Subscription, 0, len(affected))
+	for _, sub := range affected {
+		if sub != nil {
+			subs = append(subs, *sub)
+		}
+	}
+	if len(subs) == 0 {
+		return
+	}
+	// Before the hooks, so that a value recomputed once they invalidated it
+	// is read from the primary.
+	if r.replicas != nil {
+		r.replicas.Written(ctx, subs)
+	}
+	for _, fn := range r.onWrite {
+		fn(ctx, subs)
+	}
 }
 
-func (r *SubscriptionRepository) Create(sub *models.Subscription) error {
-	query := `
-		INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`
-	_, err := r.db.Exec(query, sub.ID, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, sub.CreatedAt, sub.UpdatedAt)
+func (r *SubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		return insertSubscription(ctx, tx, sub)
+	})
+	if err == nil {
+		r.written(ctx, sub)
+	}
 	return err
 }
 
-func (r *SubscriptionRepository) GetByID(id uuid.UUID) (*models.Subscription, error) {
+func (r *SubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
 	var sub models.Subscription
-	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(
+	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE id = $1 AND tenant_id = $2`
+	err := r.replicas.reader(ctx, r.db, subscriptionKey(tenant.FromContext(ctx), id)).QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(
 		&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
+	sub.TenantID = tenant.FromContext(ctx)
 	return &sub, err
 }
 
-func (r *SubscriptionRepository) Update(sub *models.Subscription) error {
-	query := `
-		UPDATE subscriptions 
-		SET service_name = $1, price = $2, start_date = $3, end_date = $4, updated_at = $5
-		WHERE id = $6
-	`
-	_, err := r.db.Exec(query, sub.ServiceName, sub.Price, sub.StartDate, sub.EndDate, sub.UpdatedAt, sub.ID)
+func (r *SubscriptionRepository) Update(ctx context.Context, sub *models.Subscription) error {
+	var old *models.Subscription
+	err := withTx(ctx, r.db, func(tx *tracedTx) (err error) {
+		old, err = updateSubscription(ctx, tx, sub)
+		return err
+	})
+	if err == nil && old != nil {
+		r.written(ctx, old, sub)
+	}
 	return err
 }
 
-func (r *SubscriptionRepository) Delete(id uuid.UUID) error {
-	query := `DELETE FROM subscriptions WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+func (r *SubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM subscriptions WHERE id = $1 AND tenant_id = $2
+		RETURNING id, service_name, price, user_id, start_date, end_date, created_at, updated_at, tenant_id
+	`
+	var deleted *models.Subscription
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		var sub models.Subscription
+		err := tx.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(
+			&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt, &sub.TenantID,
+		)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		deleted = &sub
+		return insertEvent(ctx, tx, models.EventSubscriptionDeleted, &sub)
+	})
+	if err == nil {
+		r.written(ctx, deleted)
+	}
 	return err
 }
 
-func (r *SubscriptionRepository) List(filter *models.SubscriptionFilter) ([]models.Subscription, error) {
-	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
+func (r *SubscriptionRepository) List(ctx context.Context, filter *models.SubscriptionFilter) ([]models.Subscription, error) {
+	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE tenant_id = $1`
+	args := []interface{}{tenant.FromContext(ctx)}
+	argCount := 2
 
 	if filter.UserID != "" {
 		query += fmt.Sprintf(" AND user_id = $%d", argCount)
@@ -122,7 +173,13 @@ func (r *SubscriptionRepository) List(filter *models.SubscriptionFilter) ([]mode
 		argCount++
 	}
 
-	rows, err := r.db.Query(query, args...)
+	if filter.Query != "" {
+		query += fmt.Sprintf(" AND %s ORDER BY %s, service_name, start_date", serviceNameMatch(argCount), serviceNameRank(argCount))
+		args = append(args, strings.ToLower(filter.Query), escapeLike(strings.ToLower(filter.Query)))
+		argCount += 2
+	}
+
+	rows, err := r.replicas.reader(ctx, r.db, filterKey(tenant.FromContext(ctx), filter)).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -135,45 +192,243 @@ func (r *SubscriptionRepository) List(filter *models.SubscriptionFilter) ([]mode
 		if err != nil {
 			return nil, err
 		}
+		sub.TenantID = tenant.FromContext(ctx)
 		subscriptions = append(subscriptions, sub)
 	}
 
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetTotalCost(filter *models.SubscriptionFilter) (int, error) {
-	query := `SELECT COALESCE(SUM(price), 0) FROM subscriptions WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
+// GetTotalCost sums the prices the subscriptions matching filter have in
+// the month they start, archived ones included.
+func (r *SubscriptionRepository) GetTotalCost(ctx context.Context, filter *models.SubscriptionFilter) (int, error) {
+	live, args := costConditions(filter, "start_date", 2)
+	archived, _ := costConditions(filter, "month", 2)
+	query := `
+		SELECT (SELECT COALESCE(SUM(` + priceIn("date_trunc('month', s.start_date)::date") + `), 0) FROM subscriptions s WHERE tenant_id = $1` + live + `)
+			+ (SELECT COALESCE(SUM(started_total), 0) FROM archived_totals WHERE tenant_id = $1` + archived + `)`
+	args = append([]interface{}{tenant.FromContext(ctx)}, args...)
+
+	var total int
+	err := r.replicas.reader(ctx, r.db, filterKey(tenant.FromContext(ctx), filter)).QueryRowContext(ctx, query, args...).Scan(&total)
+	return total, err
+}
+
+// costConditions renders the user, service and start month conditions of
+// filter for a table whose rows start on dateColumn, numbering arguments
+// from n.
+func costConditions(filter *models.SubscriptionFilter, dateColumn string, n int) (string, []interface{}) {
+	var conds string
+	var args []interface{}
 
 	if filter.UserID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argCount)
+		conds += fmt.Sprintf(" AND user_id = $%d", n)
 		args = append(args, filter.UserID)
-		argCount++
+		n++
 	}
 
 	if filter.ServiceName != "" {
-		query += fmt.Sprintf(" AND service_name = $%d", argCount)
+		conds += fmt.Sprintf(" AND service_name = $%d", n)
 		args = append(args, filter.ServiceName)
-		argCount++
+		n++
 	}
 
 	if filter.StartMonth != "" {
 		startDate, _ := time.Parse("01-2006", filter.StartMonth)
-		query += fmt.Sprintf(" AND start_date >= $%d", argCount)
+		conds += fmt.Sprintf(" AND %s >= $%d", dateColumn, n)
 		args = append(args, startDate)
-		argCount++
+		n++
 	}
 
 	if filter.EndMonth != "" {
 		endDate, _ := time.Parse("01-2006", filter.EndMonth)
 		endDate = endDate.AddDate(0, 1, -1)
-		query += fmt.Sprintf(" AND start_date <= $%d", argCount)
+		conds += fmt.Sprintf(" AND %s <= $%d", dateColumn, n)
 		args = append(args, endDate)
+	}
+
+	return conds, args
+}
+
+// GetMonthlyBreakdown sums, for every month in [from, to], the prices of the
+// subscriptions active during that month, archived ones included.
+func (r *SubscriptionRepository) GetMonthlyBreakdown(ctx context.Context, filter *models.SubscriptionFilter, from, to time.Time) ([]models.MonthlyCost, error) {
+	args := []interface{}{from, to, tenant.FromContext(ctx)}
+	argCount := 4
+	var live, archived string
+
+	if filter.UserID != "" {
+		live += fmt.Sprintf(" AND s.user_id = $%d", argCount)
+		archived += fmt.Sprintf(" AND a.user_id = $%d", argCount)
+		args = append(args, filter.UserID)
 		argCount++
 	}
 
-	var total int
-	err := r.db.QueryRow(query, args...).Scan(&total)
-	return total, err
+	if filter.ServiceName != "" {
+		live += fmt.Sprintf(" AND s.service_name = $%d", argCount)
+		archived += fmt.Sprintf(" AND a.service_name = $%d", argCount)
+		args = append(args, filter.ServiceName)
+		argCount++
+	}
+
+	query := `
+		SELECT m.month, COALESCE(SUM(` + priceIn("m.month") + `), 0) + COALESCE((
+			SELECT SUM(a.active_total) FROM archived_totals a
+			WHERE a.month = m.month AND a.tenant_id = $3` + archived + `
+		), 0)
+		FROM generate_series($1::date, $2::date, interval '1 month') AS m(month)
+		LEFT JOIN subscriptions s
+			ON ` + chargedIn("m.month") + `
+			AND s.tenant_id = $3` + live + `
+		GROUP BY m.month ORDER BY m.month`
+
+	rows, err := r.replicas.reader(ctx, r.db, filterKey(tenant.FromContext(ctx), filter)).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []models.MonthlyCost
+	for rows.Next() {
+		var month time.Time
+		var total int
+		if err := rows.Scan(&month, &total); err != nil {
+			return nil, err
+		}
+		months = append(months, models.MonthlyCost{Month: month.Format("01-2006"), Total: total})
+	}
+
+	return months, rows.Err()
+}
+
+// chargedIn is the condition for the subscription aliased s to be charged
+// in the month starting on the date expression month.
+func chargedIn(month string) string {
+	return "s.start_date < " + month + " + interval '1 month' AND (s.end_date IS NULL OR s.end_date >= " + month + ")"
+}
+
+// priceIn is the price of the subscription aliased s in the month starting
+// on the date expression month: that of the latest price change in effect by
+// then, or its own before the first one.
+func priceIn(month string) string {
+	return "COALESCE((SELECT p.price FROM price_changes p WHERE p.subscription_id = s.id AND p.effective_date <= " + month +
+		" ORDER BY p.effective_date DESC LIMIT 1), s.price)"
+}
+
+func insertSubscription(ctx context.Context, tx *tracedTx, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	sub.TenantID = tenant.FromContext(ctx)
+	return insertBudgetAlerts(ctx, tx, []uuid.UUID{sub.UserID}, func() ([]*models.Subscription, error) {
+		_, err := tx.ExecContext(ctx, query, sub.ID, sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, sub.CreatedAt, sub.UpdatedAt, sub.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Subscription{sub}, insertEvent(ctx, tx, models.EventSubscriptionCreated, sub)
+	})
+}
+
+// updateSubscription returns sub as it was before the update, or nil when it
+// does not exist.
+func updateSubscription(ctx context.Context, tx *tracedTx, sub *models.Subscription) (*models.Subscription, error) {
+	old, err := lockSubscription(ctx, tx, sub.ID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sub.TenantID = old.TenantID
+
+	query := `
+		UPDATE subscriptions 
+		SET service_name = $1, price = $2, start_date = $3, end_date = $4, updated_at = $5
+		WHERE id = $6 AND tenant_id = $7
+	`
+	return old, insertBudgetAlerts(ctx, tx, []uuid.UUID{sub.UserID}, func() ([]*models.Subscription, error) {
+		if _, err := tx.ExecContext(ctx, query, sub.ServiceName, sub.Price, sub.StartDate, sub.EndDate, sub.UpdatedAt, sub.ID, sub.TenantID); err != nil {
+			return nil, err
+		}
+		return []*models.Subscription{sub}, insertEvent(ctx, tx, models.EventSubscriptionUpdated, sub)
+	})
+}
+
+// ListByUserIDs returns the subscriptions of all the given users in a single
+// query. Only the service name of filter is applied.
+func (r *SubscriptionRepository) ListByUserIDs(ctx context.Context, userIDs []uuid.UUID, filter *models.SubscriptionFilter) ([]models.Subscription, error) {
+	query := `SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at FROM subscriptions WHERE user_id = ANY($1::uuid[]) AND tenant_id = $2`
+	args := []interface{}{uuidArray(userIDs), tenant.FromContext(ctx)}
+
+	if filter.ServiceName != "" {
+		query += " AND service_name = $3"
+		args = append(args, filter.ServiceName)
+	}
+
+	query += " ORDER BY user_id, start_date"
+
+	rows, err := r.replicas.reader(ctx, r.db, userKeys(tenant.FromContext(ctx), userIDs)...).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		var sub models.Subscription
+		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		sub.TenantID = tenant.FromContext(ctx)
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// GetTotalCostByUserIDs is GetTotalCost for several users at once. Users
+// without matching subscriptions are absent from the result.
+func (r *SubscriptionRepository) GetTotalCostByUserIDs(ctx context.Context, userIDs []uuid.UUID, filter *models.SubscriptionFilter) (map[uuid.UUID]int, error) {
+	others := *filter
+	others.UserID = ""
+	live, args := costConditions(&others, "start_date", 3)
+	archived, _ := costConditions(&others, "month", 3)
+	query := `
+		SELECT user_id, SUM(total) FROM (
+			SELECT user_id, ` + priceIn("date_trunc('month', s.start_date)::date") + ` AS total FROM subscriptions s
+			WHERE user_id = ANY($1::uuid[]) AND tenant_id = $2` + live + `
+			UNION ALL
+			SELECT user_id, started_total FROM archived_totals
+			WHERE user_id = ANY($1::uuid[]) AND tenant_id = $2` + archived + `
+		) t
+		GROUP BY user_id`
+	args = append([]interface{}{uuidArray(userIDs), tenant.FromContext(ctx)}, args...)
+
+	rows, err := r.replicas.reader(ctx, r.db, userKeys(tenant.FromContext(ctx), userIDs)...).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[uuid.UUID]int, len(userIDs))
+	for rows.Next() {
+		var userID uuid.UUID
+		var total int
+		if err := rows.Scan(&userID, &total); err != nil {
+			return nil, err
+		}
+		totals[userID] = total
+	}
+
+	return totals, rows.Err()
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	arr := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		arr[i] = id.String()
+	}
+	return arr
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -42,7 +43,7 @@ func TestCreateSubscription(t *testing.T) {
 		UpdatedAt:   now,
 	}
 
-	err := repo.Create(sub)
+	err := repo.Create(context.Background(), sub)
 	if err != nil {
 		t.Errorf("Failed to create subscription: %v", err)
 	}
@@ -66,9 +67,9 @@ func TestGetSubscriptionByID(t *testing.T) {
 		UpdatedAt:   now,
 	}
 
-	repo.Create(sub)
+	repo.Create(context.Background(), sub)
 
-	result, err := repo.GetByID(id)
+	result, err := repo.GetByID(context.Background(), id)
 	if err != nil {
 		t.Errorf("Failed to get subscription: %v", err)
 	}
@@ -96,17 +97,17 @@ func TestUpdateSubscription(t *testing.T) {
 		UpdatedAt:   now,
 	}
 
-	repo.Create(sub)
+	repo.Create(context.Background(), sub)
 
 	sub.Price = 1099
 	sub.UpdatedAt = time.Now()
 
-	err := repo.Update(sub)
+	err := repo.Update(context.Background(), sub)
 	if err != nil {
 		t.Errorf("Failed to update subscription: %v", err)
 	}
 
-	updated, _ := repo.GetByID(id)
+	updated, _ := repo.GetByID(context.Background(), id)
 	if updated.Price != 1099 {
 		t.Errorf("Expected price 1099, got %d", updated.Price)
 	}
@@ -130,14 +131,14 @@ func TestDeleteSubscription(t *testing.T) {
 		UpdatedAt:   now,
 	}
 
-	repo.Create(sub)
+	repo.Create(context.Background(), sub)
 
-	err := repo.Delete(id)
+	err := repo.Delete(context.Background(), id)
 	if err != nil {
 		t.Errorf("Failed to delete subscription: %v", err)
 	}
 
-	result, _ := repo.GetByID(id)
+	result, _ := repo.GetByID(context.Background(), id)
 	if result != nil {
 		t.Error("Subscription still exists after deletion")
 	}
@@ -183,14 +184,14 @@ func TestGetTotalCostWithFilters(t *testing.T) {
 	}
 
 	for _, sub := range subs {
-		repo.Create(&sub)
+		repo.Create(context.Background(), &sub)
 	}
 
 	filter := &models.SubscriptionFilter{
 		UserID: userID.String(),
 	}
 
-	total, err := repo.GetTotalCost(filter)
+	total, err := repo.GetTotalCost(context.Background(), filter)
 	if err != nil {
 		t.Errorf("Failed to get total cost: %v", err)
 	}
@@ -199,3 +200,53 @@ func TestGetTotalCostWithFilters(t *testing.T) {
 		t.Errorf("Expected total 1298, got %d", total)
 	}
 }
+
+func TestGetTotalCostByUserIDsMatchesGetTotalCost(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSubscriptionRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       999,
+		UserID:      userID,
+		StartDate:   start,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	change := &models.PriceChange{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		EffectiveDate:  start,
+		Price:          1299,
+		CreatedAt:      now,
+	}
+	if err := repo.CreatePriceChange(ctx, change); err != nil {
+		t.Fatalf("Failed to create price change: %v", err)
+	}
+
+	total, err := repo.GetTotalCost(ctx, &models.SubscriptionFilter{UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("Failed to get total cost: %v", err)
+	}
+	totals, err := repo.GetTotalCostByUserIDs(ctx, []uuid.UUID{userID}, &models.SubscriptionFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get total costs: %v", err)
+	}
+
+	if total != 1299 {
+		t.Errorf("Expected total 1299, got %d", total)
+	}
+	if totals[userID] != total {
+		t.Errorf("Expected total %d for user, got %d", total, totals[userID])
+	}
+}
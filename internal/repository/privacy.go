@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// ExportUser collects everything stored about a user of the tenant of ctx
+// from one snapshot of the primary. Archived subscriptions are not in the
+// database and are left for the caller.
+func (r *SubscriptionRepository) ExportUser(ctx context.Context, userID uuid.UUID) (*models.UserExport, error) {
+	tenantID := tenant.FromContext(ctx)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := &models.UserExport{UserID: userID, ExportedAt: time.Now().UTC()}
+
+	query := `
+		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at
+		FROM subscriptions
+		WHERE user_id = $1 AND tenant_id = $2
+		ORDER BY start_date, id
+	`
+	rows, err := tx.QueryContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var s models.Subscription
+		if err := rows.Scan(&s.ID, &s.ServiceName, &s.Price, &s.UserID, &s.StartDate, &s.EndDate, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Subscriptions = append(export.Subscriptions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(export.Subscriptions) > 0 {
+		ids := make([]uuid.UUID, len(export.Subscriptions))
+		for i, s := range export.Subscriptions {
+			ids[i] = s.ID
+		}
+		if export.PriceChanges, err = listPriceChanges(ctx, tx, tenantID, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	if export.Budgets, err = listBudgets(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT id, event_type, subscription_id, user_id, payload, created_at
+		FROM outbox_events
+		WHERE user_id = $1 AND tenant_id = $2
+		ORDER BY id
+	`
+	rows, err = tx.QueryContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.SubscriptionID, &e.UserID, &e.Payload, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Events = append(export.Events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT r.id, r.subscription_id, r.kind, r.due_date, r.channel, r.status, r.attempts, r.last_error, r.claimed_at, r.sent_at
+		FROM reminders r
+		JOIN subscriptions s ON s.id = r.subscription_id
+		WHERE s.user_id = $1 AND s.tenant_id = $2
+		ORDER BY r.id
+	`
+	rows, err = tx.QueryContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rem models.Reminder
+		err := rows.Scan(&rem.ID, &rem.SubscriptionID, &rem.Kind, &rem.DueDate, &rem.Channel, &rem.Status, &rem.Attempts, &rem.LastError, &rem.ClaimedAt, &rem.SentAt)
+		if err != nil {
+			return nil, err
+		}
+		export.Reminders = append(export.Reminders, rem)
+	}
+
+	return export, rows.Err()
+}
+
+// ErrErasureNotPending is returned when completing an erasure that is not
+// pending any more.
+var ErrErasureNotPending = errors.New("erasure is not pending")
+
+// BeginErasure records the erasure of a user of the tenant of ctx as pending
+// and returns its id. A pending erasure of the same user is resumed instead.
+func (r *SubscriptionRepository) BeginErasure(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	query := `
+		INSERT INTO erasures (id, tenant_id, user_id, receipt, status)
+		VALUES ($1, $2, $3, '{}', 'pending')
+		ON CONFLICT (tenant_id, user_id) WHERE status = 'pending' DO UPDATE SET started_at = erasures.started_at
+		RETURNING id
+	`
+	id := uuid.New()
+	err := r.db.QueryRowContext(ctx, query, id, tenant.FromContext(ctx), userID).Scan(&id)
+	return id, err
+}
+
+// AddArchivedErasure counts n more archived subscriptions removed by the
+// pending erasure id.
+func (r *SubscriptionRepository) AddArchivedErasure(ctx context.Context, id uuid.UUID, n int) error {
+	query := `UPDATE erasures SET archived_deleted = archived_deleted + $1 WHERE id = $2 AND tenant_id = $3 AND status = 'pending'`
+	_, err := r.db.ExecContext(ctx, query, n, id, tenant.FromContext(ctx))
+	return err
+}
+
+// EraseUser completes the pending erasure id of a user of the tenant of ctx
+// in one transaction: it removes their subscriptions with their price
+// changes and reminders, and their budgets. Their events are kept for the
+// history of the tenant but lose their user and payload, pending webhook
+// deliveries of them are dropped, and their archived monthly totals are
+// moved to the nil user so that tenant-wide totals do not change. The
+// receipt replaces the pending record.
+func (r *SubscriptionRepository) EraseUser(ctx context.Context, id, userID uuid.UUID) (*models.ErasureReceipt, error) {
+	tenantID := tenant.FromContext(ctx)
+	receipt := &models.ErasureReceipt{
+		ID:       id,
+		UserID:   userID,
+		ErasedAt: time.Now().UTC(),
+	}
+	var erased []*models.Subscription
+
+	err := withTx(ctx, r.db, func(tx *tracedTx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT archived_deleted FROM erasures
+			WHERE id = $1 AND user_id = $2 AND tenant_id = $3 AND status = 'pending'
+			FOR UPDATE
+		`, id, userID, tenantID).Scan(&receipt.ArchivedSubscriptionsDeleted)
+		if err == sql.ErrNoRows {
+			return ErrErasureNotPending
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			SELECT
+				(SELECT COUNT(*) FROM price_changes p JOIN subscriptions s ON s.id = p.subscription_id WHERE s.user_id = $1 AND s.tenant_id = $2),
+				(SELECT COUNT(*) FROM reminders m JOIN subscriptions s ON s.id = m.subscription_id WHERE s.user_id = $1 AND s.tenant_id = $2)
+		`, userID, tenantID).Scan(&receipt.PriceChangesDeleted, &receipt.RemindersDeleted)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			DELETE FROM subscriptions WHERE user_id = $1 AND tenant_id = $2
+			RETURNING id, service_name, user_id
+		`, userID, tenantID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			sub := &models.Subscription{TenantID: tenantID}
+			if err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.UserID); err != nil {
+				rows.Close()
+				return err
+			}
+			erased = append(erased, sub)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		receipt.SubscriptionsDeleted = len(erased)
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM budgets WHERE user_id = $1 AND tenant_id = $2`, userID, tenantID)
+		if err != nil {
+			return err
+		}
+		if receipt.BudgetsDeleted, err = affectedRows(result); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM webhook_deliveries
+			WHERE status = 'pending'
+			  AND event_id IN (SELECT id FROM outbox_events WHERE user_id = $1 AND tenant_id = $2)
+		`, userID, tenantID)
+		if err != nil {
+			return err
+		}
+		result, err = tx.ExecContext(ctx, `
+			UPDATE outbox_events SET user_id = $3, payload = '{}'
+			WHERE user_id = $1 AND tenant_id = $2
+		`, userID, tenantID, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		if receipt.EventsAnonymized, err = affectedRows(result); err != nil {
+			return err
+		}
+
+		// The services are collected before the move so that their cached
+		// totals can be invalidated.
+		rows, err = tx.QueryContext(ctx, `
+			SELECT DISTINCT service_name FROM archived_totals WHERE user_id = $1 AND tenant_id = $2
+		`, userID, tenantID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			sub := &models.Subscription{UserID: userID, TenantID: tenantID}
+			if err := rows.Scan(&sub.ServiceName); err != nil {
+				rows.Close()
+				return err
+			}
+			erased = append(erased, sub)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		err = tx.QueryRowContext(ctx, `
+			WITH moved AS (
+				DELETE FROM archived_totals WHERE user_id = $1 AND tenant_id = $2
+				RETURNING tenant_id, service_name, month, active_total, started_total
+			), merged AS (
+				INSERT INTO archived_totals (tenant_id, user_id, service_name, month, active_total, started_total)
+				SELECT tenant_id, $3::uuid, service_name, month, active_total, started_total FROM moved
+				ON CONFLICT (tenant_id, user_id, service_name, month) DO UPDATE
+				SET active_total = archived_totals.active_total + EXCLUDED.active_total,
+					started_total = archived_totals.started_total + EXCLUDED.started_total
+			)
+			SELECT COUNT(*) FROM moved
+		`, userID, tenantID, uuid.Nil).Scan(&receipt.ArchivedTotalsAnonymized)
+		if err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(receipt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE erasures SET status = 'completed', receipt = $1, erased_at = $2
+			WHERE id = $3 AND tenant_id = $4
+		`, payload, receipt.ErasedAt, id, tenantID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.written(ctx, erased...)
+	return receipt, nil
+}
+
+func affectedRows(result sql.Result) (int, error) {
+	n, err := result.RowsAffected()
+	return int(n), err
+}
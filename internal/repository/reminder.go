@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ReminderRepository struct {
+	db *tracedDB
+}
+
+func NewReminderRepository(db *sql.DB) *ReminderRepository {
+	return &ReminderRepository{db: &tracedDB{db}}
+}
+
+// ListDue returns the subscriptions of every tenant, active between from and
+// to, that end by to or started on one of renewalDays of the month, so that
+// only those with a reminder due in the horizon are loaded. It reads across
+// tenants and must run on the worker connection, whose role bypasses
+// row-level security.
+func (r *ReminderRepository) ListDue(ctx context.Context, from, to time.Time, renewalDays []int) ([]models.Subscription, error) {
+	query := `
+		SELECT id, service_name, price, user_id, start_date, end_date, created_at, updated_at, tenant_id
+		FROM subscriptions
+		WHERE start_date <= $1 AND (end_date IS NULL OR end_date >= $2)
+			AND (end_date <= $1 OR EXTRACT(DAY FROM start_date)::integer = ANY($3::integer[]))
+	`
+	rows, err := r.db.QueryContext(ctx, query, to, from, pq.Array(renewalDays))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		var sub models.Subscription
+		err := rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate, &sub.CreatedAt, &sub.UpdatedAt, &sub.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// Claim records the intent to send a reminder. It returns false when another
+// replica already sent it, is sending it right now, or it has exhausted its
+// attempts. Failed reminders and claims older than staleAfter are taken over.
+func (r *ReminderRepository) Claim(ctx context.Context, subscriptionID uuid.UUID, kind string, dueDate time.Time, channel string, maxAttempts int, staleAfter time.Duration) (*models.Reminder, error) {
+	query := `
+		INSERT INTO reminders (subscription_id, kind, due_date, channel, tenant_id)
+		SELECT $1, $2, $3, $4, tenant_id FROM subscriptions WHERE id = $1
+		ON CONFLICT (subscription_id, kind, due_date, channel) DO UPDATE
+		SET status = 'pending', attempts = reminders.attempts + 1, claimed_at = NOW(), last_error = NULL
+		WHERE (reminders.status = 'failed' AND reminders.attempts < $5)
+		   OR (reminders.status = 'pending' AND reminders.claimed_at < NOW() - make_interval(secs => $6))
+		RETURNING id, subscription_id, kind, due_date, channel, status, attempts, last_error, claimed_at, sent_at
+	`
+	var rem models.Reminder
+	err := r.db.QueryRowContext(ctx, query, subscriptionID, kind, dueDate, channel, maxAttempts, staleAfter.Seconds()).Scan(
+		&rem.ID, &rem.SubscriptionID, &rem.Kind, &rem.DueDate, &rem.Channel, &rem.Status, &rem.Attempts, &rem.LastError, &rem.ClaimedAt, &rem.SentAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &rem, err
+}
+
+func (r *ReminderRepository) MarkSent(ctx context.Context, id int64) error {
+	query := `UPDATE reminders SET status = 'sent', sent_at = NOW(), last_error = NULL WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *ReminderRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	query := `UPDATE reminders SET status = 'failed', last_error = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, reason, id)
+	return err
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// replicaCooldown is how long a replica that failed to connect is left out
+// of the rotation.
+const replicaCooldown = 30 * time.Second
+
+// ReplicaSet spreads reads over read-only replicas. Reads that may depend on
+// a write committed less than the read-your-writes window ago go to the
+// primary instead, since the replicas may not have caught up yet. Writes are
+// only known to the instance that made them.
+type ReplicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	window   time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	recent map[string]time.Time
+}
+
+type replica struct {
+	db        *tracedDB
+	downUntil atomic.Int64
+}
+
+// NewReplicaDBs opens a pool for each replica DSN of cfg, with the pool
+// settings of the primary. Connections are only made on first use, so an
+// unavailable replica does not prevent startup.
+func NewReplicaDBs(cfg *config.Config) ([]*sql.DB, error) {
+	dbs := make([]*sql.DB, 0, len(cfg.Database.Replicas))
+	for _, dsn := range cfg.Database.Replicas {
+		db, err := openDB(dsn, cfg.Tenancy.RowLevelSecurity)
+		if err != nil {
+			for _, opened := range dbs {
+				opened.Close()
+			}
+			return nil, err
+		}
+		db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+func NewReplicaSet(dbs []*sql.DB, readYourWritesWindow time.Duration) *ReplicaSet {
+	rs := &ReplicaSet{
+		window: readYourWritesWindow,
+		now:    time.Now,
+		recent: make(map[string]time.Time),
+	}
+	for _, db := range dbs {
+		rs.replicas = append(rs.replicas, &replica{db: &tracedDB{db}})
+	}
+	return rs
+}
+
+// Written records the writes of affected, so that reads of the same
+// subscriptions, users and services of their tenant see them.
+func (rs *ReplicaSet) Written(_ context.Context, affected []models.Subscription) {
+	now := rs.now()
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if len(rs.recent) > 10000 {
+		for key, at := range rs.recent {
+			if now.Sub(at) > rs.window {
+				delete(rs.recent, key)
+			}
+		}
+	}
+	for _, sub := range affected {
+		rs.recent[anyKey(sub.TenantID)] = now
+		rs.recent[subscriptionKey(sub.TenantID, sub.ID)] = now
+		rs.recent[userKey(sub.TenantID, sub.UserID.String())] = now
+		rs.recent[serviceKey(sub.TenantID, sub.ServiceName)] = now
+	}
+}
+
+// Write keys are per tenant, since a tenant never reads another's writes.
+
+func anyKey(tenantID string) string { return tenantID + "/any" }
+
+func subscriptionKey(tenantID string, id uuid.UUID) string {
+	return tenantID + "/subscription:" + id.String()
+}
+
+func serviceKey(tenantID, name string) string { return tenantID + "/service:" + name }
+
+func userKey(tenantID, id string) string {
+	if parsed, err := uuid.Parse(id); err == nil {
+		id = parsed.String()
+	}
+	return tenantID + "/user:" + id
+}
+
+func userKeys(tenantID string, ids []uuid.UUID) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userKey(tenantID, id.String())
+	}
+	return keys
+}
+
+// filterKey is the write key a query with filter depends on: writes of its
+// user, of its service when it has no user, or any write of the tenant
+// otherwise.
+func filterKey(tenantID string, filter *models.SubscriptionFilter) string {
+	switch {
+	case filter.UserID != "":
+		return userKey(tenantID, filter.UserID)
+	case filter.ServiceName != "":
+		return serviceKey(tenantID, filter.ServiceName)
+	default:
+		return anyKey(tenantID)
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that reads made with it go to the primary, for the
+// reads of a read-modify-write.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// reader returns where to send a read depending on the given write keys:
+// the primary when one of them was written within the window, ctx asks for
+// it or no replica is available, a replica otherwise.
+func (rs *ReplicaSet) reader(ctx context.Context, primary *tracedDB, keys ...string) reader {
+	if rs == nil || len(rs.replicas) == 0 || ctx.Value(primaryKey{}) != nil {
+		return primary
+	}
+
+	now := rs.now()
+	rs.mu.Lock()
+	for _, key := range keys {
+		if at, ok := rs.recent[key]; ok && now.Sub(at) <= rs.window {
+			rs.mu.Unlock()
+			return primary
+		}
+	}
+	rs.mu.Unlock()
+
+	start := rs.next.Add(1)
+	for i := range rs.replicas {
+		r := rs.replicas[(start+uint64(i))%uint64(len(rs.replicas))]
+		if now.UnixNano() >= r.downUntil.Load() {
+			return &replicaReader{replica: r, primary: primary, now: rs.now}
+		}
+	}
+	return primary
+}
+
+type reader interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// replicaReader runs a read on a replica, falling back to the primary and
+// taking the replica out of the rotation when it cannot be reached.
+type replicaReader struct {
+	replica *replica
+	primary *tracedDB
+	now     func() time.Time
+}
+
+func (r *replicaReader) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := r.replica.db.QueryContext(ctx, query, args...)
+	if r.unreachable(ctx, err) {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (r *replicaReader) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := r.replica.db.QueryRowContext(ctx, query, args...)
+	if r.unreachable(ctx, row.Err()) {
+		return r.primary.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+func (r *replicaReader) unreachable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	if !errors.Is(err, driver.ErrBadConn) && !errors.As(err, &netErr) {
+		return false
+	}
+	r.replica.downUntil.Store(r.now().Add(replicaCooldown).UnixNano())
+	return true
+}
+
+// Close closes the replica pools.
+func (rs *ReplicaSet) Close() error {
+	var errs []error
+	for _, r := range rs.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
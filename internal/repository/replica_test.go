@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+func newTestReplicaSet(t *testing.T, n int) (*ReplicaSet, *tracedDB) {
+	t.Helper()
+	open := func() *sql.DB {
+		db, err := sql.Open("postgres", "host=localhost")
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	var dbs []*sql.DB
+	for range n {
+		dbs = append(dbs, open())
+	}
+	return NewReplicaSet(dbs, 5*time.Second), &tracedDB{open()}
+}
+
+func TestReaderReadsOwnWritesFromPrimary(t *testing.T) {
+	ctx := context.Background()
+	rs, primary := newTestReplicaSet(t, 1)
+	now := time.Now()
+	rs.now = func() time.Time { return now }
+
+	sub := models.Subscription{ID: uuid.New(), UserID: uuid.New(), ServiceName: "Netflix", TenantID: "acme"}
+	other := &models.SubscriptionFilter{UserID: uuid.NewString()}
+
+	if _, ok := rs.reader(ctx, primary, filterKey("acme", other)).(*replicaReader); !ok {
+		t.Error("Expected a read to go to the replica")
+	}
+
+	rs.Written(ctx, []models.Subscription{sub})
+
+	for name, key := range map[string]string{
+		"subscription": subscriptionKey("acme", sub.ID),
+		"user":         filterKey("acme", &models.SubscriptionFilter{UserID: sub.UserID.String()}),
+		"service":      filterKey("acme", &models.SubscriptionFilter{ServiceName: "Netflix"}),
+		"unfiltered":   filterKey("acme", &models.SubscriptionFilter{}),
+	} {
+		if rs.reader(ctx, primary, key) != primary {
+			t.Errorf("Expected the %s read to go to the primary after a write", name)
+		}
+	}
+	if _, ok := rs.reader(ctx, primary, filterKey("acme", other)).(*replicaReader); !ok {
+		t.Error("Expected another user's read to go to the replica")
+	}
+	if _, ok := rs.reader(ctx, primary, subscriptionKey(tenant.Default, sub.ID)).(*replicaReader); !ok {
+		t.Error("Expected another tenant's read to go to the replica")
+	}
+
+	now = now.Add(6 * time.Second)
+	if _, ok := rs.reader(ctx, primary, subscriptionKey("acme", sub.ID)).(*replicaReader); !ok {
+		t.Error("Expected the read to go to the replica after the window")
+	}
+}
+
+func TestReaderSkipsDownReplicas(t *testing.T) {
+	ctx := context.Background()
+	rs, primary := newTestReplicaSet(t, 2)
+
+	if rs.reader(WithPrimary(ctx), primary, anyKey(tenant.Default)) != primary {
+		t.Error("Expected WithPrimary to force the primary")
+	}
+
+	rs.replicas[0].downUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	for range 4 {
+		r, ok := rs.reader(ctx, primary, anyKey(tenant.Default)).(*replicaReader)
+		if !ok || r.replica != rs.replicas[1] {
+			t.Fatal("Expected reads to go to the healthy replica")
+		}
+	}
+
+	rs.replicas[1].downUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	if rs.reader(ctx, primary, anyKey(tenant.Default)) != primary {
+		t.Error("Expected reads to go to the primary when every replica is down")
+	}
+}
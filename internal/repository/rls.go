@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"subscription-service/internal/tenant"
+
+	"github.com/lib/pq"
+)
+
+// openDB opens a pool on dsn. With row-level security, every connection
+// declares the tenant of a statement's context in app.tenant_id before
+// running it, which the policies of the tenant tables check. Contexts
+// without a tenant clear it, which hides every tenant row: the background
+// workers that span tenants use a pool of their own, opened without
+// row-level security as a role that bypasses it.
+func openDB(dsn string, rowLevelSecurity bool) (*sql.DB, error) {
+	if !rowLevelSecurity {
+		return sql.Open("postgres", dsn)
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&tenantConnector{connector}), nil
+}
+
+type tenantConnector struct {
+	driver.Connector
+}
+
+// pqConn is what database/sql uses of a lib/pq connection.
+type pqConn interface {
+	driver.Conn
+	driver.QueryerContext
+	driver.ExecerContext
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+func (c *tenantConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := conn.(pqConn)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("driver connection does not support row-level security")
+	}
+	return &tenantConn{pqConn: pc}, nil
+}
+
+// tenantConn remembers the tenant it last declared for the session so that
+// it only sets it again when a statement is made for another one. Inside a
+// transaction the setting is local to it and not remembered, since the end
+// of the transaction reverts it.
+type tenantConn struct {
+	pqConn
+	tenant string
+
+	// local is the tenant set for the open transaction, if any.
+	inTx  bool
+	local *string
+}
+
+func (c *tenantConn) setTenant(ctx context.Context) error {
+	var id string
+	if tenant.Scoped(ctx) {
+		id = tenant.FromContext(ctx)
+	}
+	current := c.tenant
+	if c.local != nil {
+		current = *c.local
+	}
+	if id == current {
+		return nil
+	}
+
+	args := []driver.NamedValue{{Ordinal: 1, Value: id}, {Ordinal: 2, Value: c.inTx}}
+	rows, err := c.pqConn.QueryContext(ctx, `SELECT set_config('app.tenant_id', $1, $2)`, args)
+	if err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if c.inTx {
+		c.local = &id
+	} else {
+		c.tenant = id
+	}
+	return nil
+}
+
+func (c *tenantConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.setTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.pqConn.QueryContext(ctx, query, args)
+}
+
+func (c *tenantConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.setTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.pqConn.ExecContext(ctx, query, args)
+}
+
+func (c *tenantConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.setTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.pqConn.PrepareContext(ctx, query)
+}
+
+func (c *tenantConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.setTenant(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := c.pqConn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &tenantTx{Tx: tx, conn: c}, nil
+}
+
+type tenantTx struct {
+	driver.Tx
+	conn *tenantConn
+}
+
+func (t *tenantTx) Commit() error {
+	t.conn.inTx, t.conn.local = false, nil
+	return t.Tx.Commit()
+}
+
+func (t *tenantTx) Rollback() error {
+	t.conn.inTx, t.conn.local = false, nil
+	return t.Tx.Rollback()
+}
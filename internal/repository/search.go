@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+)
+
+// serviceNameMatch is the condition of a service name search. $n is the
+// lowercased query and $n+1 the same query escaped for LIKE. The % operator
+// compares trigram similarity against pg_trgm.similarity_threshold (0.3 by
+// default) and can use the trigram index, unlike similarity().
+func serviceNameMatch(n int) string {
+	return fmt.Sprintf(`(lower(service_name) LIKE '%%' || $%[2]d::text || '%%' OR lower(service_name) %% $%[1]d::text)`, n, n+1)
+}
+
+// serviceNameRank orders search results: exact matches first, then prefix
+// matches, then substring matches, then by trigram similarity.
+func serviceNameRank(n int) string {
+	return fmt.Sprintf(`CASE
+			WHEN lower(service_name) = $%[1]d::text THEN 0
+			WHEN lower(service_name) LIKE $%[2]d::text || '%%' THEN 1
+			WHEN lower(service_name) LIKE '%%' || $%[2]d::text || '%%' THEN 2
+			ELSE 3
+		END, similarity(lower(service_name), $%[1]d::text) DESC`, n, n+1)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SuggestServices returns up to limit distinct service names matching query,
+// most relevant first, with the number of subscriptions to each.
+func (r *SubscriptionRepository) SuggestServices(ctx context.Context, query string, limit int) ([]models.ServiceSuggestion, error) {
+	q := fmt.Sprintf(`
+		SELECT service_name, COUNT(*)
+		FROM subscriptions
+		WHERE tenant_id = $4 AND %s
+		GROUP BY service_name
+		ORDER BY %s, COUNT(*) DESC, service_name
+		LIMIT $3
+	`, serviceNameMatch(1), serviceNameRank(1))
+	query = strings.ToLower(query)
+	tenantID := tenant.FromContext(ctx)
+
+	rows, err := r.replicas.reader(ctx, r.db, anyKey(tenantID)).QueryContext(ctx, q, query, escapeLike(query), limit, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []models.ServiceSuggestion
+	for rows.Next() {
+		var s models.ServiceSuggestion
+		if err := rows.Scan(&s.Name, &s.Subscriptions); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
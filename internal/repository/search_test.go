@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`100%_off\`); got != `100\%\_off\\` {
+		t.Errorf("Expected escaped wildcards, got %s", got)
+	}
+}
+
+func TestServiceNameMatchPlaceholders(t *testing.T) {
+	cond := serviceNameMatch(3)
+	if !strings.Contains(cond, "'%' || $4::text || '%'") || !strings.Contains(cond, "% $3::text") {
+		t.Errorf("Unexpected condition: %s", cond)
+	}
+}
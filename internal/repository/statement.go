@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// StatementLines returns the charges of a user of the tenant of ctx in the
+// month starting on month, counted as GetMonthlyBreakdown counts them so
+// that both agree: one line per live subscription, then one per service of
+// the archived totals.
+func (r *SubscriptionRepository) StatementLines(ctx context.Context, userID uuid.UUID, month time.Time) ([]models.StatementLine, error) {
+	tenantID := tenant.FromContext(ctx)
+	query := `
+		SELECT s.id, s.service_name, s.start_date, s.end_date, ` + priceIn("$3::date") + `, false
+		FROM subscriptions s
+		WHERE s.user_id = $1 AND s.tenant_id = $2 AND ` + chargedIn("$3::date") + `
+		UNION ALL
+		SELECT NULL, a.service_name, NULL, NULL, a.active_total, true
+		FROM archived_totals a
+		WHERE a.user_id = $1 AND a.tenant_id = $2 AND a.month = $3::date AND a.active_total <> 0
+		ORDER BY 6, 2, 3
+	`
+	rows, err := r.replicas.reader(ctx, r.db, userKey(tenantID, userID.String())).QueryContext(ctx, query, userID, tenantID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.StatementLine
+	for rows.Next() {
+		var line models.StatementLine
+		if err := rows.Scan(&line.SubscriptionID, &line.ServiceName, &line.StartDate, &line.EndDate, &line.Amount, &line.Archived); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("subscription-service/internal/repository")
+
+var (
+	stringLiteralRe  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteralRe = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+	whitespaceRe     = regexp.MustCompile(`\s+`)
+)
+
+// tracedDB wraps *sql.DB so that every statement is recorded as a client span.
+type tracedDB struct {
+	*sql.DB
+}
+
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return result, err
+}
+
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{tx}, nil
+}
+
+// tracedTx is the transactional counterpart of tracedDB.
+type tracedTx struct {
+	*sql.Tx
+}
+
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return result, err
+}
+
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+func (tx *tracedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := sanitizeQuery(query)
+	return tracer.Start(ctx, queryOperation(statement),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", statement),
+		),
+	)
+}
+
+func recordQueryError(span trace.Span, err error) {
+	if err == nil || err == sql.ErrNoRows {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// sanitizeQuery collapses whitespace and masks inline literals so that no
+// user data ends up in span attributes. Bind parameters ($1, $2...) are kept.
+func sanitizeQuery(query string) string {
+	query = stringLiteralRe.ReplaceAllString(query, "?")
+	query = numericLiteralRe.ReplaceAllStringFunc(query, func(literal string) string {
+		if strings.HasPrefix(literal, "$") {
+			return literal
+		}
+		return "?"
+	})
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(query, " "))
+}
+
+func queryOperation(statement string) string {
+	if i := strings.IndexByte(statement, ' '); i > 0 {
+		return strings.ToUpper(statement[:i])
+	}
+	return strings.ToUpper(statement)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import "testing"
+
+func TestSanitizeQuery(t *testing.T) {
+	query := `
+		SELECT id FROM subscriptions
+		WHERE service_name = 'Netflix' AND price > 100 AND user_id = $1 AND 1=1
+	`
+
+	got := sanitizeQuery(query)
+	want := "SELECT id FROM subscriptions WHERE service_name = ? AND price > ? AND user_id = $1 AND ?=?"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if op := queryOperation(got); op != "SELECT" {
+		t.Errorf("Expected operation SELECT, got %s", op)
+	}
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *tracedDB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: &tracedDB{db}}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, url, secret, events, active, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, hook.ID, hook.URL, hook.Secret, pq.Array(hook.Events), hook.Active, hook.CreatedAt, hook.UpdatedAt, tenant.FromContext(ctx))
+	return err
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	var hook models.Webhook
+	query := `SELECT id, url, secret, events, active, created_at, updated_at FROM webhooks WHERE id = $1 AND tenant_id = $2`
+	err := r.db.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(
+		&hook.ID, &hook.URL, &hook.Secret, pq.Array(&hook.Events), &hook.Active, &hook.CreatedAt, &hook.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &hook, err
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	query := `SELECT id, url, secret, events, active, created_at, updated_at FROM webhooks WHERE tenant_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		var hook models.Webhook
+		err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, pq.Array(&hook.Events), &hook.Active, &hook.CreatedAt, &hook.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenant.FromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FanOut turns up to limit undispatched outbox events into one delivery per
+// subscribed webhook of the event's tenant and marks the events as
+// dispatched, in one statement.
+func (r *WebhookRepository) FanOut(ctx context.Context, limit int) (int64, error) {
+	query := `
+		WITH events AS (
+			SELECT id, event_type, tenant_id FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, tenant_id)
+			SELECT w.id, e.id, e.tenant_id FROM events e
+			JOIN webhooks w ON w.tenant_id = e.tenant_id AND w.active AND (cardinality(w.events) = 0 OR e.event_type = ANY(w.events))
+			ON CONFLICT (webhook_id, event_id) DO NOTHING
+		)
+		UPDATE outbox_events SET dispatched_at = NOW() WHERE id IN (SELECT id FROM events)
+	`
+	result, err := r.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClaimDue leases up to limit due deliveries for lease so that other
+// replicas skip them while they are being sent.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, webhook_id, event_id, attempts
+		)
+		SELECT c.id, c.attempts, w.url, w.secret,
+			e.id, e.event_type, e.subscription_id, e.user_id, e.payload, e.created_at
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		JOIN outbox_events e ON e.id = c.event_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.PendingDelivery
+	for rows.Next() {
+		var d models.PendingDelivery
+		err := rows.Scan(&d.ID, &d.Attempts, &d.URL, &d.Secret,
+			&d.Event.ID, &d.Event.Type, &d.Event.SubscriptionID, &d.Event.UserID, &d.Event.Payload, &d.Event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', delivered_at = NOW(), last_status_code = $1, last_error = NULL
+		WHERE id = $2
+	`
+	_, err := r.db.ExecContext(ctx, query, statusCode, id)
+	return err
+}
+
+// MarkFailed schedules the next attempt, or moves the delivery to the dead
+// state when dead is set. statusCode is 0 when no response was received.
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, statusCode int, reason string, nextAttemptAt time.Time, dead bool) error {
+	status := models.DeliveryStatusPending
+	if dead {
+		status = models.DeliveryStatusDead
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_status_code = $2, last_error = $3, next_attempt_at = $4
+		WHERE id = $5
+	`
+	_, err := r.db.ExecContext(ctx, query, status, code, reason, nextAttemptAt, id)
+	return err
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.webhook_id, d.event_id, e.event_type, d.status, d.attempts, d.next_attempt_at,
+			d.last_status_code, d.last_error, d.created_at, d.delivered_at
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND ($2 = '' OR d.status = $2) AND w.tenant_id = $4
+		ORDER BY d.id DESC
+		LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, webhookID, status, limit, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Redeliver puts a delivery back into the queue with a fresh attempt budget.
+func (r *WebhookRepository) Redeliver(ctx context.Context, webhookID uuid.UUID, id int64) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		WHERE id = $1 AND webhook_id = $2
+			AND webhook_id IN (SELECT id FROM webhooks WHERE tenant_id = $3)
+	`
+	result, err := r.db.ExecContext(ctx, query, id, webhookID, tenant.FromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
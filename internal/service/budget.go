@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const maxBudgetCategoryLength = 50
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+type BudgetService struct {
+	repo   *repository.BudgetRepository
+	logger *logger.Logger
+}
+
+func NewBudgetService(repo *repository.BudgetRepository, logger *logger.Logger) *BudgetService {
+	return &BudgetService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Set creates or replaces the budget of userID for req.Category, the
+// overall budget when the category is empty.
+func (s *BudgetService) Set(ctx context.Context, userID string, req *models.SetBudgetRequest) (_ *models.Budget, err error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.Set")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+
+	v := &validator{}
+	category := strings.TrimSpace(req.Category)
+	if utf8.RuneCountInString(category) > maxBudgetCategoryLength {
+		v.add("category", CodeTooLong, "category must be at most %d characters", maxBudgetCategoryLength)
+	}
+	services := make([]string, 0, len(req.Services))
+	for _, name := range req.Services {
+		if name = strings.TrimSpace(name); name != "" {
+			services = append(services, name)
+		}
+	}
+	switch {
+	case category == "" && len(services) > 0:
+		v.add("services", CodeInvalidFormat, "services can only be set on a category budget")
+	case category != "" && len(services) == 0:
+		v.add("services", CodeRequired, "a category budget needs at least one service")
+	}
+	if req.Amount < 1 {
+		v.add("amount", CodeOutOfRange, "amount must be positive, got %d", req.Amount)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	budget := &models.Budget{
+		ID:        uuid.New(),
+		UserID:    id,
+		Category:  category,
+		Services:  services,
+		Amount:    req.Amount,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Upsert(ctx, budget); err != nil {
+		s.log(ctx).Error("failed to save budget", "error", err)
+		return nil, err
+	}
+
+	s.log(ctx).Info("budget saved", "user_id", id, "category", category)
+	return budget, nil
+}
+
+func (s *BudgetService) List(ctx context.Context, userID string) (_ []models.Budget, err error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.List")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+
+	budgets, err := s.repo.ListByUser(ctx, id)
+	if err != nil {
+		s.log(ctx).Error("failed to list budgets", "error", err)
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+func (s *BudgetService) Delete(ctx context.Context, userID, category string) (err error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.Delete")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return invalidArgument("invalid user id format")
+	}
+
+	deleted, err := s.repo.Delete(ctx, id, strings.TrimSpace(category))
+	if err != nil {
+		s.log(ctx).Error("failed to delete budget", "error", err)
+		return err
+	}
+	if !deleted {
+		return ErrBudgetNotFound
+	}
+
+	return nil
+}
+
+// Status projects the monthly spend of userID against each of their budgets
+// over [fromMonth, toMonth], by default the current month and the following
+// ones up to the alert horizon.
+func (s *BudgetService) Status(ctx context.Context, userID, fromMonth, toMonth string) (_ *models.UserBudgetStatus, err error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.Status")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if fromMonth != "" {
+		if from, err = time.Parse("01-2006", fromMonth); err != nil {
+			return nil, invalidArgument("invalid from month format, expected MM-YYYY")
+		}
+	}
+	to := from.AddDate(0, repository.BudgetHorizonMonths-1, 0)
+	if toMonth != "" {
+		if to, err = time.Parse("01-2006", toMonth); err != nil {
+			return nil, invalidArgument("invalid to month format, expected MM-YYYY")
+		}
+	}
+	if to.Before(from) {
+		return nil, invalidArgument("to month must not be before from month")
+	}
+	if from.AddDate(0, maxBreakdownMonths, 0).Before(to) {
+		return nil, invalidArgument(fmt.Sprintf("status is limited to %d months", maxBreakdownMonths))
+	}
+
+	budgets, err := s.repo.ListByUser(ctx, id)
+	if err != nil {
+		s.log(ctx).Error("failed to list budgets", "error", err)
+		return nil, err
+	}
+
+	status := &models.UserBudgetStatus{UserID: id, Budgets: make([]models.BudgetStatus, 0, len(budgets))}
+	for _, b := range budgets {
+		spend, err := s.repo.MonthlySpend(ctx, &b, from, to)
+		if err != nil {
+			s.log(ctx).Error("failed to compute monthly spend", "error", err)
+			return nil, err
+		}
+		status.Budgets = append(status.Budgets, budgetStatus(b, spend))
+	}
+
+	return status, nil
+}
+
+func budgetStatus(b models.Budget, spend []models.MonthlyCost) models.BudgetStatus {
+	st := models.BudgetStatus{Budget: b, Months: make([]models.BudgetMonth, 0, len(spend))}
+	for _, m := range spend {
+		month := models.BudgetMonth{Month: m.Month, Spend: m.Total}
+		if m.Total > b.Amount {
+			month.OverBudget = true
+			month.Overspend = m.Total - b.Amount
+			st.OverBudgetMonths++
+		}
+		st.Months = append(st.Months, month)
+	}
+	return st
+}
+
+func (s *BudgetService) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	bulkTokenTTL   = 15 * time.Minute
+	bulkSampleSize = 20
+)
+
+var (
+	ErrBulkOperationNotFound = errors.New("bulk operation not found, expired or already executed")
+	ErrBulkChanged           = errors.New("subscriptions matching the bulk operation changed since the preview, preview it again")
+)
+
+// PreviewBulk validates a bulk update or deletion and reports what it would
+// affect, with the token that confirms it through ExecuteBulk. An update of
+// the end date that makes subscriptions of the same user and service overlap
+// is rejected with a *ConflictError unless req allows overlaps.
+func (s *SubscriptionService) PreviewBulk(ctx context.Context, req *models.BulkRequest) (_ *models.BulkPreview, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.PreviewBulk")
+	defer func() { endSpan(span, err) }()
+
+	op, v := s.bulkOperation(req)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	match, err := s.repo.MatchBulk(ctx, op, bulkSampleSize)
+	if err != nil {
+		s.log(ctx).Error("failed to match bulk operation", "error", err)
+		return nil, err
+	}
+	if op.SetEndDate && match.EndBeforeStart > 0 {
+		v.add("end_date", CodeBeforeStart, "end date is before the start date of %d matching subscriptions", match.EndBeforeStart)
+		return nil, v.err()
+	}
+	if match.Overlaps > 0 && !op.AllowOverlap {
+		return nil, bulkConflict(match)
+	}
+
+	preview := &models.BulkPreview{
+		Action:        op.Action,
+		Matched:       match.Matched,
+		Sample:        match.Sample,
+		Overlaps:      match.Overlaps,
+		OverlapSample: match.OverlapSample,
+	}
+	if preview.Sample == nil {
+		preview.Sample = []models.Subscription{}
+	}
+	if match.Matched == 0 {
+		return preview, nil
+	}
+
+	op.ID = uuid.New()
+	op.Matched = match.Matched
+	op.ExpiresAt = time.Now().Add(bulkTokenTTL)
+	if err := s.repo.CreateBulkOperation(ctx, op, match.Hash); err != nil {
+		s.log(ctx).Error("failed to store bulk operation", "error", err)
+		return nil, err
+	}
+
+	preview.Token = &op.ID
+	preview.ExpiresAt = &op.ExpiresAt
+	return preview, nil
+}
+
+// ExecuteBulk runs the bulk operation previewed with token. A token is good
+// for one execution within bulkTokenTTL of its preview.
+func (s *SubscriptionService) ExecuteBulk(ctx context.Context, token string) (_ *models.BulkResult, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.ExecuteBulk")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(token)
+	if err != nil {
+		return nil, invalidArgument("invalid token format")
+	}
+
+	exec, err := s.repo.ExecuteBulkOperation(ctx, id)
+	switch {
+	case err != nil:
+		s.log(ctx).Error("failed to execute bulk operation", "error", err)
+		return nil, err
+	case exec == nil:
+		return nil, ErrBulkOperationNotFound
+	case exec.Changed != "":
+		s.log(ctx).Info("bulk operation rejected", "token", id, "reason", exec.Changed)
+		return nil, ErrBulkChanged
+	}
+
+	s.log(ctx).Info("bulk operation executed", "token", id, "action", exec.Operation.Action, "affected", exec.Affected)
+	return &models.BulkResult{Action: exec.Operation.Action, Affected: exec.Affected}, nil
+}
+
+// bulkConflict lists the subscriptions of the overlaps in match once each.
+func bulkConflict(match *repository.BulkMatch) *ConflictError {
+	seen := make(map[uuid.UUID]bool)
+	var conflicts []models.Subscription
+	for _, o := range match.OverlapSample {
+		for _, sub := range []models.Subscription{o.First, o.Second} {
+			if !seen[sub.ID] {
+				seen[sub.ID] = true
+				conflicts = append(conflicts, sub)
+			}
+		}
+	}
+	return &ConflictError{
+		Message:   fmt.Sprintf("bulk update makes %d pairs of subscriptions to the same service overlap, preview it with allow_overlap to proceed", match.Overlaps),
+		Conflicts: conflicts,
+	}
+}
+
+// bulkOperation turns req into an operation, collecting its problems in the
+// returned validator. The fields of the update are reported without prefix,
+// those of the filter prefixed with "filter.".
+func (s *SubscriptionService) bulkOperation(req *models.BulkRequest) (*models.BulkOperation, *validator) {
+	v := newValidator(s.rules)
+	op := &models.BulkOperation{Action: req.Action, Filter: req.Filter, AllowOverlap: req.AllowOverlap}
+
+	switch req.Action {
+	case models.BulkActionDelete:
+	case models.BulkActionUpdate:
+		if req.Update == nil || (req.Update.Price == nil && req.Update.EndDate == nil) {
+			v.add("update", CodeRequired, "update must set price or end_date")
+			break
+		}
+		if req.Update.Price != nil {
+			v.price(*req.Update.Price)
+			op.Price = req.Update.Price
+		}
+		if req.Update.EndDate != nil {
+			op.SetEndDate = true
+			if *req.Update.EndDate != "" {
+				if end, ok := v.month("end_date", *req.Update.EndDate); ok {
+					op.EndDate = &end
+				}
+			}
+		}
+	case "":
+		v.add("action", CodeRequired, "action is required")
+	default:
+		v.add("action", CodeInvalidFormat, "action must be %s or %s", models.BulkActionUpdate, models.BulkActionDelete)
+	}
+
+	f := &op.Filter
+	f.Query = strings.TrimSpace(f.Query)
+	if *f == (models.SubscriptionFilter{}) {
+		v.add("filter", CodeRequired, "filter must not be empty")
+	}
+	if f.UserID != "" {
+		if _, err := uuid.Parse(f.UserID); err != nil {
+			v.add("filter.user_id", CodeInvalidFormat, "invalid user id format")
+		}
+	}
+	if _, err := time.Parse("01-2006", f.StartMonth); f.StartMonth != "" && err != nil {
+		v.add("filter.start_month", CodeInvalidFormat, "invalid start month format, expected MM-YYYY")
+	}
+	if _, err := time.Parse("01-2006", f.EndMonth); f.EndMonth != "" && err != nil {
+		v.add("filter.end_month", CodeInvalidFormat, "invalid end month format, expected MM-YYYY")
+	}
+	if utf8.RuneCountInString(f.Query) > maxSearchQueryLen {
+		v.add("filter.q", CodeTooLong, "search query must be at most %d characters", maxSearchQueryLen)
+	}
+
+	return op, v
+}
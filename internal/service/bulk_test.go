@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestBulkOperationValidation(t *testing.T) {
+	s := &SubscriptionService{rules: config.ValidationConfig{MinPrice: 0, MaxPrice: 1000}}
+	price, end := 500, ""
+
+	op, v := s.bulkOperation(&models.BulkRequest{
+		Action: models.BulkActionUpdate,
+		Filter: models.SubscriptionFilter{ServiceName: "Netflix"},
+		Update: &models.BulkUpdate{Price: &price, EndDate: &end},
+	})
+	if err := v.err(); err != nil {
+		t.Fatalf("Expected a valid operation, got %v", err)
+	}
+	if !op.SetEndDate || op.EndDate != nil || *op.Price != 500 {
+		t.Errorf("Expected the price set and the end date cleared, got %+v", op)
+	}
+
+	_, v = s.bulkOperation(&models.BulkRequest{Action: models.BulkActionDelete})
+	if len(v.fields) != 1 || v.fields[0].Field != "filter" {
+		t.Errorf("Expected an empty filter to be rejected, got %+v", v.fields)
+	}
+
+	_, v = s.bulkOperation(&models.BulkRequest{
+		Action: models.BulkActionUpdate,
+		Filter: models.SubscriptionFilter{UserID: "nope", StartMonth: "2025-01"},
+	})
+	if len(v.fields) != 3 {
+		t.Errorf("Expected errors for the update, user id and start month, got %+v", v.fields)
+	}
+}
+
+func TestBulkConflictListsEachSubscriptionOnce(t *testing.T) {
+	a := models.Subscription{ID: uuid.New()}
+	b := models.Subscription{ID: uuid.New()}
+	c := models.Subscription{ID: uuid.New()}
+
+	conflict := bulkConflict(&repository.BulkMatch{
+		Overlaps:      5,
+		OverlapSample: []models.SubscriptionOverlap{{First: a, Second: b}, {First: a, Second: c}},
+	})
+	if len(conflict.Conflicts) != 3 {
+		t.Errorf("Expected 3 conflicting subscriptions, got %d", len(conflict.Conflicts))
+	}
+	if !strings.Contains(conflict.Message, "5 pairs") {
+		t.Errorf("Expected the number of overlaps in the message, got %q", conflict.Message)
+	}
+}
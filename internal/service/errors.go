@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+
+	"subscription-service/internal/models"
+)
+
+var ErrNotFound = errors.New("subscription not found")
+
+// ArgumentError reports a request that can never succeed as sent, as
+// opposed to a failure of the storage layer.
+type ArgumentError struct {
+	Message string
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Message
+}
+
+func invalidArgument(message string) error {
+	return &ArgumentError{Message: message}
+}
+
+// IsArgumentError reports whether err was caused by invalid input.
+func IsArgumentError(err error) bool {
+	var argErr *ArgumentError
+	var validationErr *ValidationError
+	return errors.As(err, &argErr) || errors.As(err, &validationErr)
+}
+
+// ConflictError reports a write rejected because it would overlap existing
+// subscriptions, listed in Conflicts.
+type ConflictError struct {
+	Message   string
+	Conflicts []models.Subscription
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const defaultForecastMonths = 12
+
+var ErrPriceChangeNotFound = errors.New("price change not found")
+
+// SchedulePriceChange sets the price of subscription id from the month of
+// req.EffectiveDate onwards. Only the current and future months can be
+// scheduled, within the period of the subscription.
+func (s *SubscriptionService) SchedulePriceChange(ctx context.Context, id string, req *models.SchedulePriceChangeRequest) (_ *models.PriceChange, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.SchedulePriceChange")
+	defer func() { endSpan(span, err) }()
+
+	sub, err := s.GetByID(repository.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newValidator(s.rules)
+	v.price(req.Price)
+	if effective, ok := v.month("effective_date", req.EffectiveDate); ok {
+		current := time.Date(v.now.Year(), v.now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		switch {
+		case effective.Before(current):
+			v.add("effective_date", CodeTooEarly, "effective date must not be before %s", current.Format("01-2006"))
+		case effective.Before(sub.StartDate):
+			v.add("effective_date", CodeBeforeStart, "effective date must not be before start date")
+		case sub.EndDate != nil && effective.After(*sub.EndDate):
+			v.add("effective_date", CodeOutOfRange, "effective date must not be after end date")
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	effective, _ := time.Parse("01-2006", req.EffectiveDate)
+	change := &models.PriceChange{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		EffectiveDate:  effective,
+		Price:          req.Price,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.repo.CreatePriceChange(ctx, change); err != nil {
+		s.log(ctx).Error("failed to schedule price change", "error", err)
+		return nil, err
+	}
+
+	s.log(ctx).Info("price change scheduled", "id", sub.ID, "effective_date", req.EffectiveDate)
+	return change, nil
+}
+
+func (s *SubscriptionService) ListPriceChanges(ctx context.Context, id string) (_ []models.PriceChange, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.ListPriceChanges")
+	defer func() { endSpan(span, err) }()
+
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.ListPriceChanges(ctx, []uuid.UUID{sub.ID})
+	if err != nil {
+		s.log(ctx).Error("failed to list price changes", "error", err)
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (s *SubscriptionService) DeletePriceChange(ctx context.Context, id, changeID string) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.DeletePriceChange")
+	defer func() { endSpan(span, err) }()
+
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return invalidArgument("invalid id format")
+	}
+	changeUUID, err := uuid.Parse(changeID)
+	if err != nil {
+		return invalidArgument("invalid price change id format")
+	}
+
+	deleted, err := s.repo.DeletePriceChange(ctx, subID, changeUUID)
+	if err != nil {
+		s.log(ctx).Error("failed to delete price change", "error", err)
+		return err
+	}
+	if !deleted {
+		return ErrPriceChangeNotFound
+	}
+
+	return nil
+}
+
+// Forecast projects the spend on the subscriptions matching the user and
+// service of filter over the current month and the following ones, taking
+// end dates and scheduled price changes into account. A months of zero
+// selects the default of a year.
+func (s *SubscriptionService) Forecast(ctx context.Context, filter *models.SubscriptionFilter, months int) (_ *models.MonthlyBreakdown, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Forecast")
+	defer func() { endSpan(span, err) }()
+
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			return nil, invalidArgument("invalid user id format")
+		}
+	}
+	if months == 0 {
+		months = defaultForecastMonths
+	}
+	if months < 1 || months > maxBreakdownMonths {
+		return nil, invalidArgument(fmt.Sprintf("months must be between 1 and %d", maxBreakdownMonths))
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	subs, err := s.repo.ListActiveSince(ctx, filter, from)
+	if err != nil {
+		s.log(ctx).Error("failed to list subscriptions", "error", err)
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	changes, err := s.repo.ListPriceChanges(ctx, ids)
+	if err != nil {
+		s.log(ctx).Error("failed to list price changes", "error", err)
+		return nil, err
+	}
+
+	return project(subs, changes, from, months), nil
+}
+
+// project sums, for each of the months starting with from, the price every
+// subscription has in that month. changes must be ordered by effective date.
+func project(subs []models.Subscription, changes []models.PriceChange, from time.Time, months int) *models.MonthlyBreakdown {
+	bySub := make(map[uuid.UUID][]models.PriceChange)
+	for _, c := range changes {
+		bySub[c.SubscriptionID] = append(bySub[c.SubscriptionID], c)
+	}
+
+	forecast := &models.MonthlyBreakdown{Months: make([]models.MonthlyCost, months)}
+	for i := range forecast.Months {
+		month := from.AddDate(0, i, 0)
+		total := 0
+		for _, sub := range subs {
+			if month.Before(sub.StartDate) || (sub.EndDate != nil && month.After(*sub.EndDate)) {
+				continue
+			}
+			price := sub.Price
+			for _, c := range bySub[sub.ID] {
+				if c.EffectiveDate.After(month) {
+					break
+				}
+				price = c.Price
+			}
+			total += price
+		}
+		forecast.Months[i] = models.MonthlyCost{Month: month.Format("01-2006"), Total: total}
+		forecast.Total += total
+	}
+
+	return forecast
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func month(m time.Month, y int) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestProject(t *testing.T) {
+	end := month(time.March, 2026)
+	ending := models.Subscription{ID: uuid.New(), Price: 100, StartDate: month(time.January, 2025), EndDate: &end}
+	rising := models.Subscription{ID: uuid.New(), Price: 500, StartDate: month(time.February, 2026)}
+	changes := []models.PriceChange{
+		{SubscriptionID: rising.ID, EffectiveDate: month(time.April, 2026), Price: 600},
+		{SubscriptionID: rising.ID, EffectiveDate: month(time.June, 2026), Price: 700},
+	}
+
+	forecast := project([]models.Subscription{ending, rising}, changes, month(time.January, 2026), 6)
+
+	want := []int{100, 600, 600, 600, 600, 700}
+	if len(forecast.Months) != len(want) {
+		t.Fatalf("Expected %d months, got %d", len(want), len(forecast.Months))
+	}
+	sum := 0
+	for i, m := range forecast.Months {
+		if m.Total != want[i] {
+			t.Errorf("Expected %d in %s, got %d", want[i], m.Month, m.Total)
+		}
+		sum += want[i]
+	}
+	if forecast.Months[0].Month != "01-2026" {
+		t.Errorf("Expected first month 01-2026, got %s", forecast.Months[0].Month)
+	}
+	if forecast.Total != sum {
+		t.Errorf("Expected total %d, got %d", sum, forecast.Total)
+	}
+}
+
+func TestForecastRejectsInvalidMonths(t *testing.T) {
+	svc := newTestService(t)
+
+	for _, months := range []int{-1, maxBreakdownMonths + 1} {
+		if _, err := svc.Forecast(context.Background(), &models.SubscriptionFilter{}, months); !IsArgumentError(err) {
+			t.Errorf("Expected argument error for %d months, got %v", months, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"subscription-service/internal/models"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/google/uuid"
+)
+
+// PatchFormat is the kind of document accepted by Patch.
+type PatchFormat int
+
+const (
+	// MergePatch is a JSON Merge Patch (RFC 7386): null removes a field.
+	MergePatch PatchFormat = iota
+	// JSONPatch is a JSON Patch (RFC 6902), a list of operations.
+	JSONPatch
+)
+
+// ErrPatchTestFailed is returned when a test operation of a JSON Patch does
+// not match the current subscription.
+var ErrPatchTestFailed = errors.New("patch test operation failed")
+
+// Replace overwrites subscription id with req. Fields left out of req are
+// not kept: a missing end date makes the subscription open ended and missing
+// required fields are rejected. Partial updates go through Patch.
+func (s *SubscriptionService) Replace(ctx context.Context, id string, req *models.ReplaceSubscriptionRequest) (_ *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Replace")
+	defer func() { endSpan(span, err) }()
+
+	existing, err := s.current(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, existing, req)
+}
+
+// Patch applies patch to the ReplaceSubscriptionRequest representation of
+// subscription id and replaces the subscription with the result.
+func (s *SubscriptionService) Patch(ctx context.Context, id string, format PatchFormat, patch []byte, allowOverlap bool) (_ *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Patch")
+	defer func() { endSpan(span, err) }()
+
+	existing, err := s.current(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(replacement(existing))
+	if err != nil {
+		return nil, err
+	}
+	patched, err := applyPatch(format, doc, patch)
+	if err != nil {
+		s.log(ctx).Error("failed to apply patch", "error", err)
+		return nil, err
+	}
+
+	var req models.ReplaceSubscriptionRequest
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, invalidArgument("invalid patched subscription: " + err.Error())
+	}
+	req.AllowOverlap = allowOverlap
+
+	return s.replace(ctx, existing, &req)
+}
+
+func (s *SubscriptionService) replace(ctx context.Context, existing *models.Subscription, req *models.ReplaceSubscriptionRequest) (*models.Subscription, error) {
+	before := *existing
+
+	v := newValidator(s.rules)
+	existing.ServiceName = v.serviceName(req.ServiceName)
+	if req.Price == nil {
+		v.add("price", CodeRequired, "price is required")
+	} else {
+		v.price(*req.Price)
+		existing.Price = *req.Price
+	}
+	if req.UserID != "" {
+		if userID := v.userID(req.UserID); userID != uuid.Nil && userID != existing.UserID {
+			v.add("user_id", CodeImmutable, "user id cannot be changed")
+		}
+	}
+	startDate, periodOK := v.month("start_date", req.StartDate)
+	if periodOK {
+		// An unchanged start date stays valid even if the rules got stricter.
+		if !startDate.Equal(existing.StartDate) {
+			v.startDate(startDate)
+		}
+		existing.StartDate = startDate
+	}
+	existing.EndDate = nil
+	if req.EndDate != "" {
+		endDate, ok := v.month("end_date", req.EndDate)
+		if ok {
+			existing.EndDate = &endDate
+		}
+		periodOK = periodOK && ok
+	}
+	if periodOK {
+		v.period(existing.StartDate, existing.EndDate)
+	}
+	if err := v.err(); err != nil {
+		s.log(ctx).Error("invalid subscription replacement", "error", err)
+		return nil, err
+	}
+
+	return s.save(ctx, &before, existing, req.AllowOverlap)
+}
+
+// replacement is the ReplaceSubscriptionRequest that leaves sub unchanged.
+func replacement(sub *models.Subscription) *models.ReplaceSubscriptionRequest {
+	price := sub.Price
+	req := &models.ReplaceSubscriptionRequest{
+		ServiceName: sub.ServiceName,
+		Price:       &price,
+		UserID:      sub.UserID.String(),
+		StartDate:   sub.StartDate.Format("01-2006"),
+	}
+	if sub.EndDate != nil {
+		req.EndDate = sub.EndDate.Format("01-2006")
+	}
+	return req
+}
+
+func applyPatch(format PatchFormat, doc, patch []byte) ([]byte, error) {
+	switch format {
+	case MergePatch:
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, invalidArgument("invalid merge patch")
+		}
+		return patched, nil
+	case JSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, invalidArgument("invalid json patch")
+		}
+		patched, err := ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, ErrPatchTestFailed
+		}
+		if err != nil {
+			return nil, invalidArgument("failed to apply json patch: " + err.Error())
+		}
+		return patched, nil
+	default:
+		return nil, invalidArgument("unsupported patch format")
+	}
+}
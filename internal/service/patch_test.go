@@ -0,0 +1,69 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func patchedReplacement(t *testing.T, format PatchFormat, sub *models.Subscription, patch string) (*models.ReplaceSubscriptionRequest, error) {
+	t.Helper()
+	doc, err := json.Marshal(replacement(sub))
+	if err != nil {
+		t.Fatalf("Failed to encode subscription: %v", err)
+	}
+	patched, err := applyPatch(format, doc, []byte(patch))
+	if err != nil {
+		return nil, err
+	}
+	var req models.ReplaceSubscriptionRequest
+	if err := json.Unmarshal(patched, &req); err != nil {
+		t.Fatalf("Failed to decode patched subscription: %v", err)
+	}
+	return &req, nil
+}
+
+func TestMergePatchClearsEndDate(t *testing.T) {
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{ServiceName: "Netflix", Price: 400, UserID: uuid.New(), StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: &end}
+
+	req, err := patchedReplacement(t, MergePatch, sub, `{"end_date": null, "price": 500}`)
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	if req.EndDate != "" {
+		t.Errorf("Expected the end date to be cleared, got %s", req.EndDate)
+	}
+	if req.Price == nil || *req.Price != 500 {
+		t.Errorf("Expected price 500, got %v", req.Price)
+	}
+	if req.ServiceName != "Netflix" || req.StartDate != "01-2025" {
+		t.Errorf("Expected other fields to be kept, got %+v", req)
+	}
+}
+
+func TestJSONPatchTestOperation(t *testing.T) {
+	sub := &models.Subscription{ServiceName: "Netflix", Price: 400, UserID: uuid.New(), StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	req, err := patchedReplacement(t, JSONPatch, sub, `[{"op": "test", "path": "/price", "value": 400}, {"op": "add", "path": "/end_date", "value": "12-2025"}]`)
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	if req.EndDate != "12-2025" {
+		t.Errorf("Expected end date 12-2025, got %s", req.EndDate)
+	}
+
+	_, err = patchedReplacement(t, JSONPatch, sub, `[{"op": "test", "path": "/price", "value": 999}, {"op": "replace", "path": "/price", "value": 1}]`)
+	if !errors.Is(err, ErrPatchTestFailed) {
+		t.Errorf("Expected ErrPatchTestFailed, got %v", err)
+	}
+
+	if _, err := patchedReplacement(t, JSONPatch, sub, `{"op": "replace"}`); !IsArgumentError(err) {
+		t.Errorf("Expected an argument error for a malformed patch, got %v", err)
+	}
+}
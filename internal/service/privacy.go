@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+
+	"subscription-service/internal/archive"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// PrivacyService exports and erases all the data held about a user, live
+// and archived.
+type PrivacyService struct {
+	repo     *repository.SubscriptionRepository
+	archiver *archive.Archiver
+	logger   *logger.Logger
+}
+
+func NewPrivacyService(repo *repository.SubscriptionRepository, archiver *archive.Archiver, logger *logger.Logger) *PrivacyService {
+	return &PrivacyService{
+		repo:     repo,
+		archiver: archiver,
+		logger:   logger,
+	}
+}
+
+func (s *PrivacyService) Export(ctx context.Context, userID string) (_ *models.UserExport, err error) {
+	ctx, span := tracer.Start(ctx, "PrivacyService.Export")
+	defer func() { endSpan(span, err) }()
+	log := logger.FromContext(ctx, s.logger)
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+
+	export, err := s.repo.ExportUser(ctx, id)
+	if err != nil {
+		log.Error("failed to export user data", "error", err)
+		return nil, err
+	}
+	if export.ArchivedSubscriptions, err = s.archiver.UserRecords(ctx, id); err != nil {
+		log.Error("failed to export archived user data", "error", err)
+		return nil, err
+	}
+
+	log.Info("user data exported", "user_id", id)
+	return export, nil
+}
+
+// Erase records a pending erasure before it removes the user from the
+// archives and then from the database. An erasure that fails in between
+// stays pending and is resumed by the next one of the same user; removing
+// the user from the archives again finds nothing left.
+func (s *PrivacyService) Erase(ctx context.Context, userID string) (_ *models.ErasureReceipt, err error) {
+	ctx, span := tracer.Start(ctx, "PrivacyService.Erase")
+	defer func() { endSpan(span, err) }()
+	log := logger.FromContext(ctx, s.logger)
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+
+	erasure, err := s.repo.BeginErasure(ctx, id)
+	if err != nil {
+		log.Error("failed to record erasure", "error", err)
+		return nil, err
+	}
+	archived, err := s.archiver.EraseUser(ctx, id)
+	if err != nil {
+		log.Error("failed to erase archived user data", "erasure", erasure, "error", err)
+		return nil, err
+	}
+	if err := s.repo.AddArchivedErasure(ctx, erasure, archived); err != nil {
+		log.Error("failed to record erased archived user data", "erasure", erasure, "error", err)
+		return nil, err
+	}
+	receipt, err := s.repo.EraseUser(ctx, erasure, id)
+	if err != nil {
+		log.Error("failed to erase user data", "error", err)
+		return nil, err
+	}
+
+	log.Info("user data erased", "user_id", id, "receipt", receipt.ID)
+	return receipt, nil
+}
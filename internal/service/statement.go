@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Statement lists the charges of a user in month, given as MM-YYYY. Its
+// total is the one of the month in GetMonthlyBreakdown for the user.
+func (s *SubscriptionService) Statement(ctx context.Context, userID, month string) (_ *models.Statement, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Statement")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, invalidArgument("invalid user id format")
+	}
+	start, err := time.Parse("01-2006", month)
+	if err != nil {
+		return nil, invalidArgument("invalid month format, expected MM-YYYY")
+	}
+
+	lines, err := s.repo.StatementLines(ctx, id, start)
+	if err != nil {
+		s.log(ctx).Error("failed to get statement lines", "error", err)
+		return nil, err
+	}
+
+	statement := &models.Statement{
+		UserID:      id,
+		Month:       month,
+		Lines:       lines,
+		GeneratedAt: time.Now().UTC(),
+	}
+	if statement.Lines == nil {
+		statement.Lines = []models.StatementLine{}
+	}
+	for _, line := range lines {
+		statement.Total += line.Amount
+	}
+	return statement, nil
+}
@@ -1,55 +1,84 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"subscription-service/internal/cache"
+	"subscription-service/internal/config"
 	"subscription-service/internal/logger"
 	"subscription-service/internal/models"
 	"subscription-service/internal/repository"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("subscription-service/internal/service")
+
+const (
+	defaultBreakdownMonths = 12
+	maxBreakdownMonths     = 120
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+	maxSearchQueryLen   = 100
 )
 
 type SubscriptionService struct {
 	repo   *repository.SubscriptionRepository
+	rules  config.ValidationConfig
+	totals *cache.Totals
 	logger *logger.Logger
 }
 
-func NewSubscriptionService(repo *repository.SubscriptionRepository, logger *logger.Logger) *SubscriptionService {
+// NewSubscriptionService returns the service; totals may be nil to compute
+// every total from the database.
+func NewSubscriptionService(repo *repository.SubscriptionRepository, rules config.ValidationConfig, totals *cache.Totals, logger *logger.Logger) *SubscriptionService {
 	return &SubscriptionService{
 		repo:   repo,
+		rules:  rules,
+		totals: totals,
 		logger: logger,
 	}
 }
 
-func (s *SubscriptionService) Create(req *models.CreateSubscriptionRequest) (*models.Subscription, error) {
-	startDate, err := time.Parse("01-2006", req.StartDate)
-	if err != nil {
-		s.logger.Error("failed to parse start date", "error", err)
-		return nil, errors.New("invalid start date format, expected MM-YYYY")
-	}
+func (s *SubscriptionService) Create(ctx context.Context, req *models.CreateSubscriptionRequest) (_ *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Create")
+	defer func() { endSpan(span, err) }()
 
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		s.logger.Error("failed to parse user id", "error", err)
-		return nil, errors.New("invalid user id format")
+	v := newValidator(s.rules)
+	serviceName := v.serviceName(req.ServiceName)
+	v.price(req.Price)
+	userID := v.userID(req.UserID)
+	startDate, startOK := v.month("start_date", req.StartDate)
+	if startOK {
+		v.startDate(startDate)
 	}
-
 	var endDate *time.Time
 	if req.EndDate != "" {
-		parsed, err := time.Parse("01-2006", req.EndDate)
-		if err != nil {
-			s.logger.Error("failed to parse end date", "error", err)
-			return nil, errors.New("invalid end date format, expected MM-YYYY")
+		if parsed, ok := v.month("end_date", req.EndDate); ok {
+			endDate = &parsed
 		}
-		endDate = &parsed
+	}
+	if startOK {
+		v.period(startDate, endDate)
+	}
+	if err := v.err(); err != nil {
+		s.log(ctx).Error("invalid subscription", "error", err)
+		return nil, err
 	}
 
 	now := time.Now()
 	subscription := &models.Subscription{
 		ID:          uuid.New(),
-		ServiceName: req.ServiceName,
+		ServiceName: serviceName,
 		Price:       req.Price,
 		UserID:      userID,
 		StartDate:   startDate,
@@ -58,122 +87,294 @@ func (s *SubscriptionService) Create(req *models.CreateSubscriptionRequest) (*mo
 		UpdatedAt:   now,
 	}
 
-	if err := s.repo.Create(subscription); err != nil {
-		s.logger.Error("failed to create subscription", "error", err)
+	create := s.repo.CreateExclusive
+	if req.AllowOverlap {
+		create = s.repo.Create
+	}
+	if err := create(ctx, subscription); err != nil {
+		if conflict := asConflict(err); conflict != nil {
+			return nil, conflict
+		}
+		s.log(ctx).Error("failed to create subscription", "error", err)
 		return nil, err
 	}
 
-	s.logger.Info("subscription created", "id", subscription.ID)
+	s.log(ctx).Info("subscription created", "id", subscription.ID)
 	return subscription, nil
 }
 
-func (s *SubscriptionService) GetByID(id string) (*models.Subscription, error) {
+func (s *SubscriptionService) GetByID(ctx context.Context, id string) (_ *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetByID")
+	defer func() { endSpan(span, err) }()
+
 	uuidID, err := uuid.Parse(id)
 	if err != nil {
-		s.logger.Error("failed to parse id", "error", err)
-		return nil, errors.New("invalid id format")
+		s.log(ctx).Error("failed to parse id", "error", err)
+		return nil, invalidArgument("invalid id format")
 	}
 
-	subscription, err := s.repo.GetByID(uuidID)
+	subscription, err := s.repo.GetByID(ctx, uuidID)
 	if err != nil {
-		s.logger.Error("failed to get subscription", "error", err)
+		s.log(ctx).Error("failed to get subscription", "error", err)
 		return nil, err
 	}
 
 	if subscription == nil {
-		return nil, errors.New("subscription not found")
+		return nil, ErrNotFound
 	}
 
 	return subscription, nil
 }
 
-func (s *SubscriptionService) Update(id string, req *models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+// current returns subscription id as stored on the primary, to be updated.
+func (s *SubscriptionService) current(ctx context.Context, id string) (*models.Subscription, error) {
 	uuidID, err := uuid.Parse(id)
 	if err != nil {
-		s.logger.Error("failed to parse id", "error", err)
-		return nil, errors.New("invalid id format")
+		s.log(ctx).Error("failed to parse id", "error", err)
+		return nil, invalidArgument("invalid id format")
 	}
 
-	existing, err := s.repo.GetByID(uuidID)
+	existing, err := s.repo.GetByID(repository.WithPrimary(ctx), uuidID)
 	if err != nil {
-		s.logger.Error("failed to get subscription", "error", err)
+		s.log(ctx).Error("failed to get subscription", "error", err)
 		return nil, err
 	}
 
 	if existing == nil {
-		return nil, errors.New("subscription not found")
+		return nil, ErrNotFound
 	}
+	return existing, nil
+}
 
-	if req.ServiceName != "" {
-		existing.ServiceName = req.ServiceName
-	}
-	if req.Price != nil {
-		existing.Price = *req.Price
+// save stores the validated edit of before into sub.
+func (s *SubscriptionService) save(ctx context.Context, before, sub *models.Subscription, allowOverlap bool) (*models.Subscription, error) {
+	sub.UpdatedAt = time.Now()
+
+	// Only a new period or service can introduce an overlap; other edits
+	// stay possible on subscriptions that already overlap.
+	update := s.repo.UpdateExclusive
+	if allowOverlap || !periodChanged(before, sub) {
+		update = s.repo.Update
 	}
-	if req.StartDate != "" {
-		startDate, err := time.Parse("01-2006", req.StartDate)
-		if err != nil {
-			s.logger.Error("failed to parse start date", "error", err)
-			return nil, errors.New("invalid start date format")
+	if err := update(ctx, sub); err != nil {
+		if conflict := asConflict(err); conflict != nil {
+			return nil, conflict
 		}
-		existing.StartDate = startDate
-	}
-	if req.EndDate != nil {
-		if *req.EndDate == "" {
-			existing.EndDate = nil
-		} else {
-			endDate, err := time.Parse("01-2006", *req.EndDate)
-			if err != nil {
-				s.logger.Error("failed to parse end date", "error", err)
-				return nil, errors.New("invalid end date format")
-			}
-			existing.EndDate = &endDate
-		}
-	}
-
-	existing.UpdatedAt = time.Now()
-
-	if err := s.repo.Update(existing); err != nil {
-		s.logger.Error("failed to update subscription", "error", err)
+		s.log(ctx).Error("failed to update subscription", "error", err)
 		return nil, err
 	}
 
-	s.logger.Info("subscription updated", "id", id)
-	return existing, nil
+	s.log(ctx).Info("subscription updated", "id", sub.ID)
+	return sub, nil
 }
 
-func (s *SubscriptionService) Delete(id string) error {
+func (s *SubscriptionService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Delete")
+	defer func() { endSpan(span, err) }()
+
 	uuidID, err := uuid.Parse(id)
 	if err != nil {
-		s.logger.Error("failed to parse id", "error", err)
-		return errors.New("invalid id format")
+		s.log(ctx).Error("failed to parse id", "error", err)
+		return invalidArgument("invalid id format")
 	}
 
-	if err := s.repo.Delete(uuidID); err != nil {
-		s.logger.Error("failed to delete subscription", "error", err)
+	if err := s.repo.Delete(ctx, uuidID); err != nil {
+		s.log(ctx).Error("failed to delete subscription", "error", err)
 		return err
 	}
 
-	s.logger.Info("subscription deleted", "id", id)
+	s.log(ctx).Info("subscription deleted", "id", id)
 	return nil
 }
 
-func (s *SubscriptionService) List(filter *models.SubscriptionFilter) ([]models.Subscription, error) {
-	subscriptions, err := s.repo.List(filter)
+func (s *SubscriptionService) List(ctx context.Context, filter *models.SubscriptionFilter) (_ []models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.List")
+	defer func() { endSpan(span, err) }()
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	if utf8.RuneCountInString(filter.Query) > maxSearchQueryLen {
+		return nil, invalidArgument(fmt.Sprintf("search query must be at most %d characters", maxSearchQueryLen))
+	}
+
+	subscriptions, err := s.repo.List(ctx, filter)
 	if err != nil {
-		s.logger.Error("failed to list subscriptions", "error", err)
+		s.log(ctx).Error("failed to list subscriptions", "error", err)
 		return nil, err
 	}
 
 	return subscriptions, nil
 }
 
-func (s *SubscriptionService) GetTotalCost(filter *models.SubscriptionFilter) (int, error) {
-	total, err := s.repo.GetTotalCost(filter)
+func (s *SubscriptionService) GetTotalCost(ctx context.Context, filter *models.SubscriptionFilter) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetTotalCost")
+	defer func() { endSpan(span, err) }()
+
+	total, err := s.totals.Total(ctx, filter, func() (int, error) {
+		return s.repo.GetTotalCost(ctx, filter)
+	})
 	if err != nil {
-		s.logger.Error("failed to get total cost", "error", err)
+		s.log(ctx).Error("failed to get total cost", "error", err)
 		return 0, err
 	}
 
 	return total, nil
 }
+
+// SuggestServices completes a partial service name for autocomplete. A limit
+// of zero selects the default.
+func (s *SubscriptionService) SuggestServices(ctx context.Context, query string, limit int) (_ []models.ServiceSuggestion, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.SuggestServices")
+	defer func() { endSpan(span, err) }()
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, invalidArgument("q is required")
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, invalidArgument(fmt.Sprintf("search query must be at most %d characters", maxSearchQueryLen))
+	}
+	if limit == 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit < 1 || limit > maxSuggestLimit {
+		return nil, invalidArgument(fmt.Sprintf("limit must be between 1 and %d", maxSuggestLimit))
+	}
+
+	suggestions, err := s.repo.SuggestServices(ctx, query, limit)
+	if err != nil {
+		s.log(ctx).Error("failed to suggest services", "error", err)
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// ListOverlaps returns the pairs of overlapping subscriptions matching the
+// user and service of filter.
+func (s *SubscriptionService) ListOverlaps(ctx context.Context, filter *models.SubscriptionFilter) (_ []models.SubscriptionOverlap, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.ListOverlaps")
+	defer func() { endSpan(span, err) }()
+
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			return nil, invalidArgument("invalid user id format")
+		}
+	}
+
+	overlaps, err := s.repo.ListOverlaps(ctx, filter)
+	if err != nil {
+		s.log(ctx).Error("failed to list overlaps", "error", err)
+		return nil, err
+	}
+
+	return overlaps, nil
+}
+
+// ListByUsers groups the subscriptions of userIDs by user, loading them in
+// one round trip.
+func (s *SubscriptionService) ListByUsers(ctx context.Context, userIDs []uuid.UUID, filter *models.SubscriptionFilter) (_ map[uuid.UUID][]models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.ListByUsers")
+	defer func() { endSpan(span, err) }()
+
+	subscriptions, err := s.repo.ListByUserIDs(ctx, userIDs, filter)
+	if err != nil {
+		s.log(ctx).Error("failed to list subscriptions by users", "error", err)
+		return nil, err
+	}
+
+	byUser := make(map[uuid.UUID][]models.Subscription, len(userIDs))
+	for _, sub := range subscriptions {
+		byUser[sub.UserID] = append(byUser[sub.UserID], sub)
+	}
+	return byUser, nil
+}
+
+// GetTotalCostByUsers is GetTotalCost for several users in one round trip.
+func (s *SubscriptionService) GetTotalCostByUsers(ctx context.Context, userIDs []uuid.UUID, filter *models.SubscriptionFilter) (_ map[uuid.UUID]int, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetTotalCostByUsers")
+	defer func() { endSpan(span, err) }()
+
+	totals, err := s.repo.GetTotalCostByUserIDs(ctx, userIDs, filter)
+	if err != nil {
+		s.log(ctx).Error("failed to get total cost by users", "error", err)
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+func (s *SubscriptionService) GetMonthlyBreakdown(ctx context.Context, filter *models.SubscriptionFilter) (_ *models.MonthlyBreakdown, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetMonthlyBreakdown")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if filter.EndMonth != "" {
+		to, err = time.Parse("01-2006", filter.EndMonth)
+		if err != nil {
+			return nil, invalidArgument("invalid end month format, expected MM-YYYY")
+		}
+	}
+
+	from := to.AddDate(0, -(defaultBreakdownMonths - 1), 0)
+	if filter.StartMonth != "" {
+		from, err = time.Parse("01-2006", filter.StartMonth)
+		if err != nil {
+			return nil, invalidArgument("invalid start month format, expected MM-YYYY")
+		}
+	}
+
+	if to.Before(from) {
+		return nil, invalidArgument("end month must not be before start month")
+	}
+	if from.AddDate(0, maxBreakdownMonths, 0).Before(to) {
+		return nil, invalidArgument(fmt.Sprintf("breakdown is limited to %d months", maxBreakdownMonths))
+	}
+
+	months, err := s.totals.MonthlyBreakdown(ctx, filter, from, to, func() ([]models.MonthlyCost, error) {
+		return s.repo.GetMonthlyBreakdown(ctx, filter, from, to)
+	})
+	if err != nil {
+		s.log(ctx).Error("failed to get monthly breakdown", "error", err)
+		return nil, err
+	}
+
+	breakdown := &models.MonthlyBreakdown{Months: months}
+	for _, m := range months {
+		breakdown.Total += m.Total
+	}
+	return breakdown, nil
+}
+
+func periodChanged(before, after *models.Subscription) bool {
+	if before.ServiceName != after.ServiceName || !before.StartDate.Equal(after.StartDate) {
+		return true
+	}
+	if before.EndDate == nil || after.EndDate == nil {
+		return before.EndDate != after.EndDate
+	}
+	return !before.EndDate.Equal(*after.EndDate)
+}
+
+func asConflict(err error) *ConflictError {
+	var overlap *repository.OverlapError
+	if !errors.As(err, &overlap) {
+		return nil
+	}
+	return &ConflictError{
+		Message:   "subscription overlaps an existing subscription to the same service",
+		Conflicts: overlap.Conflicts,
+	}
+}
+
+func (s *SubscriptionService) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
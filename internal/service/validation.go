@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"subscription-service/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Codes of FieldError, stable for clients to match on.
+const (
+	CodeRequired          = "required"
+	CodeInvalidFormat     = "invalid_format"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeOutOfRange        = "out_of_range"
+	CodeTooEarly          = "too_early"
+	CodeTooFarInFuture    = "too_far_in_future"
+	CodeBeforeStart       = "before_start"
+	CodeImmutable         = "immutable"
+)
+
+// serviceNamePunctuation lists the characters allowed in a service name
+// besides letters, digits and spaces.
+const serviceNamePunctuation = "-+.,&'!:()/_#@*"
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError carries every problem found in a request, so that clients
+// can fix them all in one go.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+type validator struct {
+	rules  config.ValidationConfig
+	now    time.Time
+	fields []FieldError
+}
+
+func newValidator(rules config.ValidationConfig) *validator {
+	return &validator{rules: rules, now: time.Now()}
+}
+
+func (v *validator) add(field, code, format string, args ...any) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// serviceName checks name and returns it without surrounding spaces.
+func (v *validator) serviceName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		v.add("service_name", CodeRequired, "service name is required")
+		return name
+	}
+	if n := utf8.RuneCountInString(name); n > v.rules.ServiceNameMaxLength {
+		v.add("service_name", CodeTooLong, "service name must be at most %d characters, got %d", v.rules.ServiceNameMaxLength, n)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(serviceNamePunctuation, r) {
+			v.add("service_name", CodeInvalidCharacters, "service name must not contain %q", r)
+			break
+		}
+	}
+	return name
+}
+
+func (v *validator) price(price int) {
+	if price < v.rules.MinPrice || price > v.rules.MaxPrice {
+		v.add("price", CodeOutOfRange, "price must be between %d and %d, got %d", v.rules.MinPrice, v.rules.MaxPrice, price)
+	}
+}
+
+func (v *validator) userID(raw string) uuid.UUID {
+	if raw == "" {
+		v.add("user_id", CodeRequired, "user id is required")
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		v.add("user_id", CodeInvalidFormat, "invalid user id format")
+	}
+	return id
+}
+
+// month parses a required MM-YYYY field.
+func (v *validator) month(field, raw string) (time.Time, bool) {
+	if raw == "" {
+		v.add(field, CodeRequired, "%s is required", strings.ReplaceAll(field, "_", " "))
+		return time.Time{}, false
+	}
+	t, err := time.Parse("01-2006", raw)
+	if err != nil {
+		v.add(field, CodeInvalidFormat, "invalid %s format, expected MM-YYYY", strings.ReplaceAll(field, "_", " "))
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (v *validator) startDate(start time.Time) {
+	if start.Year() < v.rules.MinStartYear {
+		v.add("start_date", CodeTooEarly, "start date must not be before %d", v.rules.MinStartYear)
+	}
+	latest := time.Date(v.now.Year(), v.now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, v.rules.MaxStartMonthsAhead, 0)
+	if start.After(latest) {
+		v.add("start_date", CodeTooFarInFuture, "start date must not be after %s", latest.Format("01-2006"))
+	}
+}
+
+func (v *validator) period(start time.Time, end *time.Time) {
+	if end != nil && end.Before(start) {
+		v.add("end_date", CodeBeforeStart, "end date must not be before start date")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+)
+
+func newTestService(t *testing.T) *SubscriptionService {
+	t.Helper()
+	log, err := logger.NewWithWriter(io.Discard, "error", "text")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return NewSubscriptionService(nil, config.Default().Validation, nil, log)
+}
+
+func TestCreateReportsAllFieldErrors(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.Create(context.Background(), &models.CreateSubscriptionRequest{
+		ServiceName: "Netflix\x00",
+		Price:       5000000,
+		UserID:      "not-a-uuid",
+		StartDate:   "07-2025",
+		EndDate:     "01-2025",
+	})
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	codes := map[string]string{}
+	for _, f := range validation.Fields {
+		codes[f.Field] = f.Code
+	}
+	expected := map[string]string{
+		"service_name": CodeInvalidCharacters,
+		"price":        CodeOutOfRange,
+		"user_id":      CodeInvalidFormat,
+		"end_date":     CodeBeforeStart,
+	}
+	for field, code := range expected {
+		if codes[field] != code {
+			t.Errorf("Expected %s for %s, got %q", code, field, codes[field])
+		}
+	}
+	if len(validation.Fields) != len(expected) {
+		t.Errorf("Expected %d field errors, got %v", len(expected), validation.Fields)
+	}
+	if !IsArgumentError(err) {
+		t.Errorf("Expected validation errors to be argument errors")
+	}
+}
+
+func TestValidatorStartDateBounds(t *testing.T) {
+	rules := config.Default().Validation
+
+	tests := []struct {
+		start time.Time
+		code  string
+	}{
+		{time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), CodeTooEarly},
+		{time.Now().AddDate(0, rules.MaxStartMonthsAhead+2, 0), CodeTooFarInFuture},
+		{time.Now(), ""},
+	}
+
+	for _, tt := range tests {
+		v := newValidator(rules)
+		v.startDate(tt.start)
+
+		var got string
+		if len(v.fields) > 0 {
+			got = v.fields[0].Code
+		}
+		if got != tt.code {
+			t.Errorf("Expected %q for %s, got %q", tt.code, tt.start.Format("01-2006"), got)
+		}
+	}
+}
+
+func TestValidatorServiceName(t *testing.T) {
+	rules := config.Default().Validation
+
+	v := newValidator(rules)
+	if name := v.serviceName("  Yandex Plus  "); name != "Yandex Plus" || len(v.fields) != 0 {
+		t.Errorf("Expected trimmed valid name, got %q with %v", name, v.fields)
+	}
+
+	v = newValidator(rules)
+	v.serviceName("   ")
+	if len(v.fields) != 1 || v.fields[0].Code != CodeRequired {
+		t.Errorf("Expected required error, got %v", v.fields)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+const maxDeliveriesListed = 100
+
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	guard  *webhook.Guard
+	logger *logger.Logger
+}
+
+// NewWebhookService returns a service that only registers webhooks whose
+// host resolves to addresses guard allows.
+func NewWebhookService(repo *repository.WebhookRepository, guard *webhook.Guard, logger *logger.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		guard:  guard,
+		logger: logger,
+	}
+}
+
+func (s *WebhookService) Create(ctx context.Context, req *models.CreateWebhookRequest) (*models.CreateWebhookResponse, error) {
+	log := logger.FromContext(ctx, s.logger)
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("invalid webhook url, expected absolute http or https url")
+	}
+	if err := s.guard.CheckURL(ctx, target); err != nil {
+		log.Warn("webhook url refused", "host", target.Hostname(), "error", err)
+		return nil, err
+	}
+
+	for _, event := range req.Events {
+		if !slices.Contains(models.EventTypes, event) {
+			return nil, errors.New("unknown event type " + event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Error("failed to generate webhook secret", "error", err)
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	events := req.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	now := time.Now()
+	hook := &models.Webhook{
+		ID:        uuid.New(),
+		URL:       target.String(),
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.repo.Create(ctx, hook); err != nil {
+		log.Error("failed to create webhook", "error", err)
+		return nil, err
+	}
+
+	log.Info("webhook created", "id", hook.ID)
+	return &models.CreateWebhookResponse{Webhook: *hook, Secret: secret}, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.Webhook, error) {
+	hooks, err := s.repo.List(ctx)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("failed to list webhooks", "error", err)
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id string) error {
+	log := logger.FromContext(ctx, s.logger)
+
+	hookID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid id format")
+	}
+
+	deleted, err := s.repo.Delete(ctx, hookID)
+	if err != nil {
+		log.Error("failed to delete webhook", "error", err)
+		return err
+	}
+	if !deleted {
+		return errors.New("webhook not found")
+	}
+
+	log.Info("webhook deleted", "id", id)
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, id, status string) ([]models.WebhookDelivery, error) {
+	hookID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid id format")
+	}
+
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+	default:
+		return nil, errors.New("invalid delivery status")
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, hookID, status, maxDeliveriesListed)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("failed to list webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookService) Redeliver(ctx context.Context, id string, deliveryID int64) error {
+	log := logger.FromContext(ctx, s.logger)
+
+	hookID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid id format")
+	}
+
+	found, err := s.repo.Redeliver(ctx, hookID, deliveryID)
+	if err != nil {
+		log.Error("failed to schedule redelivery", "error", err)
+		return err
+	}
+	if !found {
+		return errors.New("delivery not found")
+	}
+
+	log.Info("webhook delivery scheduled for redelivery", "webhook_id", id, "delivery_id", deliveryID)
+	return nil
+}
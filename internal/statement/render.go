@@ -0,0 +1,186 @@
+// Package statement renders monthly statements as downloadable CSV, HTML and
+// PDF documents.
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const (
+	ContentTypeCSV  = "text/csv"
+	ContentTypeHTML = "text/html"
+	ContentTypePDF  = "application/pdf"
+)
+
+// Extension returns the file extension of documents of contentType.
+func Extension(contentType string) string {
+	switch contentType {
+	case ContentTypeCSV:
+		return "csv"
+	case ContentTypeHTML:
+		return "html"
+	case ContentTypePDF:
+		return "pdf"
+	}
+	return ""
+}
+
+// Write renders st to w as a document of contentType.
+func Write(w io.Writer, contentType string, st *models.Statement) error {
+	switch contentType {
+	case ContentTypeCSV:
+		return WriteCSV(w, st)
+	case ContentTypeHTML:
+		return WriteHTML(w, st)
+	case ContentTypePDF:
+		return WritePDF(w, st)
+	}
+	return fmt.Errorf("unsupported statement format %q", contentType)
+}
+
+// WriteCSV writes one row per line of st after a header, and the total in a
+// last row.
+func WriteCSV(w io.Writer, st *models.Statement) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"service_name", "subscription_id", "start_date", "end_date", "archived", "amount"}); err != nil {
+		return err
+	}
+	for _, line := range st.Lines {
+		var id string
+		if line.SubscriptionID != nil {
+			id = line.SubscriptionID.String()
+		}
+		row := []string{csvCell(line.ServiceName), id, month(line.StartDate), month(line.EndDate), strconv.FormatBool(line.Archived), strconv.Itoa(line.Amount)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write([]string{"Total", "", "", "", "", strconv.Itoa(st.Total)}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell prefixes value with a quote when it starts like a formula, so that
+// spreadsheets opening the statement show it as text instead of running it.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{"period": period}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.4em; text-align: left; }
+td.amount, th.amount { text-align: right; }
+tfoot td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>User {{.Statement.UserID}}<br>Generated {{.Statement.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+<table>
+<thead><tr><th>Service</th><th>Period</th><th class="amount">Amount</th></tr></thead>
+<tbody>
+{{- range .Statement.Lines}}
+<tr><td>{{.ServiceName}}</td><td>{{period .}}</td><td class="amount">{{.Amount}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot><tr><td colspan="2">Total</td><td class="amount">{{.Statement.Total}}</td></tr></tfoot>
+</table>
+</body>
+</html>
+`))
+
+func WriteHTML(w io.Writer, st *models.Statement) error {
+	return htmlTemplate.Execute(w, struct {
+		Title     string
+		Statement *models.Statement
+	}{title(st), st})
+}
+
+// WritePDF lays st out on A4 pages with the Go fonts, which cover Latin,
+// Cyrillic and Greek service names.
+func WritePDF(w io.Writer, st *models.Statement) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	pdf.SetTitle(title(st), true)
+	pdf.AddPage()
+
+	pdf.SetFont("go", "B", 16)
+	pdf.CellFormat(0, 10, title(st), "", 1, "L", false, 0, "")
+	pdf.SetFont("go", "", 10)
+	pdf.CellFormat(0, 6, "User "+st.UserID.String(), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Generated "+st.GeneratedAt.Format("2006-01-02 15:04 MST"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{90, 60, 40}
+	pdf.SetFont("go", "B", 10)
+	for i, header := range []string{"Service", "Period", "Amount"} {
+		align := "L"
+		if i == 2 {
+			align = "R"
+		}
+		pdf.CellFormat(widths[i], 8, header, "B", 0, align, false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("go", "", 10)
+	for _, line := range st.Lines {
+		pdf.CellFormat(widths[0], 7, line.ServiceName, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 7, period(line), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 7, strconv.Itoa(line.Amount), "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont("go", "B", 10)
+	pdf.CellFormat(widths[0]+widths[1], 8, "Total", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(widths[2], 8, strconv.Itoa(st.Total), "T", 1, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func title(st *models.Statement) string {
+	if m, err := time.Parse("01-2006", st.Month); err == nil {
+		return "Statement for " + m.Format("January 2006")
+	}
+	return "Statement for " + st.Month
+}
+
+func period(line models.StatementLine) string {
+	switch {
+	case line.Archived:
+		return "archived"
+	case line.EndDate == nil:
+		return "since " + month(line.StartDate)
+	default:
+		return month(line.StartDate) + " – " + month(line.EndDate)
+	}
+}
+
+func month(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("01-2006")
+}
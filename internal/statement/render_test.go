@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func testStatement() *models.Statement {
+	id := uuid.New()
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Statement{
+		UserID: uuid.New(),
+		Month:  "10-2026",
+		Lines: []models.StatementLine{
+			{SubscriptionID: &id, ServiceName: "Яндекс Плюс", StartDate: &start, Amount: 400},
+			{ServiceName: "<b>Netflix</b>", Amount: 999, Archived: true},
+		},
+		Total:       1399,
+		GeneratedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testStatement()); err != nil {
+		t.Fatalf("Failed to write csv: %v", err)
+	}
+
+	rows := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(rows) != 4 {
+		t.Fatalf("Expected a header, 2 lines and a total, got %d rows", len(rows))
+	}
+	if !strings.HasPrefix(rows[1], "Яндекс Плюс,") || !strings.HasSuffix(rows[1], ",03-2025,,false,400") {
+		t.Errorf("Unexpected line: %s", rows[1])
+	}
+	if rows[3] != "Total,,,,,1399" {
+		t.Errorf("Unexpected total row: %s", rows[3])
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	st := testStatement()
+	names := []string{"=1+1", "+Plus", "-Minus", "@SUM(A1:A9)", "\tTab", "\rReturn", "Safe-name"}
+	st.Lines = nil
+	for _, name := range names {
+		st.Lines = append(st.Lines, models.StatementLine{ServiceName: name, Amount: 1})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, st); err != nil {
+		t.Fatalf("Failed to write csv: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read csv: %v", err)
+	}
+	for i, name := range names {
+		want := "'" + name
+		if name == "Safe-name" {
+			want = name
+		}
+		if got := records[i+1][0]; got != want {
+			t.Errorf("Expected cell %q, got %q", want, got)
+		}
+	}
+}
+
+func TestWriteHTMLEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, testStatement()); err != nil {
+		t.Fatalf("Failed to write html: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "<b>Netflix</b>") {
+		t.Error("Expected service names to be escaped")
+	}
+	if !strings.Contains(out, "Statement for October 2026") || !strings.Contains(out, "since 03-2025") {
+		t.Errorf("Expected the title and the period in the document, got %s", out)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, testStatement()); err != nil {
+		t.Fatalf("Failed to write pdf: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("Expected a PDF document")
+	}
+}
@@ -0,0 +1,117 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"subscription-service/internal/config"
+)
+
+var (
+	ErrMissing       = errors.New("tenant is required")
+	ErrInvalidTenant = errors.New("invalid tenant id")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+// Resolver finds the tenant of a request. With tenancy disabled every
+// request belongs to Default. Otherwise the tenant comes from a claim of an
+// HS256-signed bearer token when a token secret is configured, and from a
+// header when it is not; a header cannot override a token.
+type Resolver struct {
+	enabled bool
+	header  string
+	secret  []byte
+	claim   string
+	now     func() time.Time
+}
+
+func NewResolver(cfg config.TenancyConfig) *Resolver {
+	return &Resolver{
+		enabled: cfg.Enabled,
+		header:  cfg.Header,
+		secret:  []byte(cfg.TokenSecret),
+		claim:   cfg.TokenClaim,
+		now:     time.Now,
+	}
+}
+
+// Header is the name of the header the tenant is read from.
+func (r *Resolver) Header() string {
+	return r.header
+}
+
+// Resolve returns the tenant given the tenant header and the Authorization
+// header of a request.
+func (r *Resolver) Resolve(header, authorization string) (string, error) {
+	if !r.enabled {
+		return Default, nil
+	}
+
+	id := strings.TrimSpace(header)
+	if len(r.secret) > 0 {
+		token, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok {
+			return "", ErrMissing
+		}
+		var err error
+		if id, err = r.claimFrom(strings.TrimSpace(token)); err != nil {
+			return "", err
+		}
+	}
+
+	if id == "" {
+		return "", ErrMissing
+	}
+	if !Valid(id) {
+		return "", ErrInvalidTenant
+	}
+	return id, nil
+}
+
+// claimFrom verifies an HS256 JWT and returns its tenant claim.
+func (r *Resolver) claimFrom(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return "", ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", ErrInvalidToken
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", ErrInvalidToken
+	}
+	if exp, ok := claims["exp"].(float64); ok && r.now().Unix() >= int64(exp) {
+		return "", ErrInvalidToken
+	}
+	id, _ := claims[r.claim].(string)
+	return id, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,41 @@
+// Package tenant carries the tenant a request acts for. Every tenant sees
+// only its own subscriptions, budgets, webhooks and events.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default is the tenant of single-tenant deployments and of the data that
+// existed before tenancy was introduced.
+const Default = "default"
+
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid reports whether id can name a tenant: up to 63 lowercase letters,
+// digits, dashes and underscores, starting with a letter or digit.
+func Valid(id string) bool {
+	return idRe.MatchString(id)
+}
+
+type contextKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, Default when none was set.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok {
+		return id
+	}
+	return Default
+}
+
+// Scoped reports whether ctx was given a tenant. Background workers act for
+// every tenant and have none.
+func Scoped(ctx context.Context) bool {
+	_, ok := ctx.Value(contextKey{}).(string)
+	return ok
+}
@@ -0,0 +1,85 @@
+package tenant
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"subscription-service/internal/config"
+)
+
+func signToken(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestFromContextDefaults(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != Default || Scoped(ctx) {
+		t.Errorf("Expected an unscoped default tenant, got %s", got)
+	}
+	if got := FromContext(WithID(ctx, "acme")); got != "acme" {
+		t.Errorf("Expected acme, got %s", got)
+	}
+}
+
+func TestResolveHeader(t *testing.T) {
+	disabled := NewResolver(config.TenancyConfig{Header: "X-Tenant-ID"})
+	if got, err := disabled.Resolve("acme", ""); err != nil || got != Default {
+		t.Errorf("Expected the default tenant with tenancy disabled, got %s, %v", got, err)
+	}
+
+	r := NewResolver(config.TenancyConfig{Enabled: true, Header: "X-Tenant-ID"})
+	if got, err := r.Resolve(" acme ", ""); err != nil || got != "acme" {
+		t.Errorf("Expected acme, got %s, %v", got, err)
+	}
+	if _, err := r.Resolve("", ""); !errors.Is(err, ErrMissing) {
+		t.Errorf("Expected ErrMissing, got %v", err)
+	}
+	if _, err := r.Resolve("Acme Corp", ""); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("Expected ErrInvalidTenant, got %v", err)
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	r := NewResolver(config.TenancyConfig{Enabled: true, Header: "X-Tenant-ID", TokenSecret: "secret", TokenClaim: "tenant_id"})
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	token := signToken(t, "secret", map[string]any{"tenant_id": "acme", "exp": now.Add(time.Hour).Unix()})
+	if got, err := r.Resolve("globex", "Bearer "+token); err != nil || got != "acme" {
+		t.Errorf("Expected the tenant of the token, got %s, %v", got, err)
+	}
+
+	if _, err := r.Resolve("globex", ""); !errors.Is(err, ErrMissing) {
+		t.Errorf("Expected the header alone to be rejected, got %v", err)
+	}
+
+	forged := signToken(t, "other", map[string]any{"tenant_id": "acme"})
+	if _, err := r.Resolve("", "Bearer "+forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a forged token to be rejected, got %v", err)
+	}
+
+	expired := signToken(t, "secret", map[string]any{"tenant_id": "acme", "exp": now.Add(-time.Minute).Unix()})
+	if _, err := r.Resolve("", "Bearer "+expired); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected an expired token to be rejected, got %v", err)
+	}
+
+	unscoped := signToken(t, "secret", map[string]any{"sub": "user"})
+	if _, err := r.Resolve("", "Bearer "+unscoped); !errors.Is(err, ErrMissing) {
+		t.Errorf("Expected a token without the claim to be rejected, got %v", err)
+	}
+}
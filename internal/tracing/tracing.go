@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"subscription-service/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const ServiceName = "subscription-service"
+
+// Init installs the global tracer provider and W3C trace-context propagator.
+// Spans are sent over OTLP/HTTP when an endpoint is configured, otherwise they
+// are written to the configured file (or stdout). Metrics are only exported
+// over OTLP/HTTP, to the same collector. The returned function flushes pending
+// spans and metrics and must be called on shutdown.
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewSchemaless(attribute.String("service.name", ServiceName))
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var meterProvider *sdkmetric.MeterProvider
+	if cfg.Tracing.OTLPEndpoint != "" {
+		metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(metricsEndpoint(cfg.Tracing.OTLPEndpoint)))
+		if err != nil {
+			provider.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to create otlp metric exporter: %w", err)
+		}
+		meterProvider = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+			sdkmetric.WithResource(res),
+		)
+		otel.SetMeterProvider(meterProvider)
+	}
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if meterProvider != nil {
+			if metricErr := meterProvider.Shutdown(ctx); err == nil {
+				err = metricErr
+			}
+		}
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// metricsEndpoint derives the OTLP metrics URL from the traces one, which may
+// be given with or without the /v1/traces signal path.
+func metricsEndpoint(tracesURL string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(tracesURL, "/"), "/v1/traces")
+	return base + "/v1/metrics"
+}
+
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, io.Closer, error) {
+	if cfg.Tracing.OTLPEndpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Tracing.OTLPEndpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, nil, nil
+	}
+
+	if cfg.Tracing.File == "" {
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	}
+
+	file, err := os.OpenFile(cfg.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+	}
+	return exporter, file, nil
+}
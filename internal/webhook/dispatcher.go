@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/health"
+	"subscription-service/internal/logger"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+)
+
+// Dispatcher moves outbox events to registered webhooks. Each tick it fans
+// new events out into deliveries and sends the ones that are due, retrying
+// failures with exponential backoff until MaxAttempts is reached.
+type Dispatcher struct {
+	repo   *repository.WebhookRepository
+	client *http.Client
+	cfg    config.WebhooksConfig
+	logger *logger.Logger
+	worker *health.Worker
+}
+
+// NewDispatcher returns a dispatcher that only connects to the addresses
+// guard allows.
+func NewDispatcher(repo *repository.WebhookRepository, cfg config.WebhooksConfig, guard *Guard, logger *logger.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: guard.Client(cfg.Timeout),
+		cfg:    cfg,
+		logger: logger,
+		worker: health.NewWorker(3*cfg.PollInterval + cfg.Timeout),
+	}
+}
+
+func (d *Dispatcher) Worker() *health.Worker {
+	return d.worker
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer d.worker.Stop()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("webhook dispatch failed", "error", err)
+		}
+		d.worker.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if _, err := d.repo.FanOut(ctx, d.cfg.BatchSize); err != nil {
+		return fmt.Errorf("failed to fan out events: %w", err)
+	}
+
+	deliveries, err := d.repo.ClaimDue(ctx, d.cfg.BatchSize, d.cfg.Timeout+d.cfg.LeaseMargin)
+	if err != nil {
+		return fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		d.deliver(ctx, delivery)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.PendingDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.repo.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			d.logger.Error("failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	dead := delivery.Attempts >= d.cfg.MaxAttempts
+	next := time.Now().Add(Backoff(delivery.Attempts, d.cfg.BackoffBase, d.cfg.BackoffMax))
+	if dead {
+		d.logger.Warn("webhook delivery moved to dead letter", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", err)
+	} else {
+		d.logger.Info("webhook delivery failed, will retry", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "next_attempt_at", next, "error", err)
+	}
+
+	if markErr := d.repo.MarkFailed(ctx, delivery.ID, statusCode, err.Error(), next, dead); markErr != nil {
+		d.logger.Error("failed to record webhook failure", "delivery_id", delivery.ID, "error", markErr)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.PendingDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, now, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the delay before the attempt following the given one:
+// base doubled per attempt, capped at max, with up to 20% random jitter.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"subscription.created"}`)
+
+	signature := Sign("secret", ts, body)
+	if !Verify("secret", ts, body, signature) {
+		t.Error("Expected signature to verify")
+	}
+	if Verify("other", ts, body, signature) {
+		t.Error("Expected signature with wrong secret to fail")
+	}
+	if Verify("secret", ts.Add(time.Second), body, signature) {
+		t.Error("Expected signature with different timestamp to fail")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := 10*time.Second, time.Minute
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := Backoff(tt.attempt, base, max)
+		if got < tt.want || got > tt.want+tt.want/5 {
+			t.Errorf("Backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.want, tt.want+tt.want/5)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for a webhook host that is or resolves to
+// an internal address.
+var ErrForbiddenAddress = errors.New("webhook url resolves to a forbidden address")
+
+// internalNetworks are refused on top of the loopback, private, link-local,
+// multicast and unspecified addresses known to the net package.
+var internalNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"100.64.0.0/10",  // carrier-grade NAT
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, broadcast included
+	"64:ff9b::/96",   // NAT64, embeds IPv4 addresses
+	"64:ff9b:1::/48", // local-use NAT64
+	"2001:db8::/32",  // documentation
+	"100::/64",       // discard
+)
+
+// Guard decides which addresses webhooks may be delivered to, so that
+// registering a webhook cannot be used to reach the internal network of the
+// service. Addresses in the allowed networks are accepted even when they are
+// internal.
+type Guard struct {
+	allowed []*net.IPNet
+}
+
+// NewGuard returns a guard accepting the given CIDR networks on top of the
+// public addresses.
+func NewGuard(allowedNetworks []string) (*Guard, error) {
+	g := &Guard{}
+	for _, cidr := range allowedNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+		g.allowed = append(g.allowed, network)
+	}
+	return g, nil
+}
+
+// Allowed reports whether webhooks may be delivered to ip.
+func (g *Guard) Allowed(ip net.IP) bool {
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL resolves the host of target and fails with ErrForbiddenAddress if
+// any of its addresses is not allowed.
+func (g *Guard) CheckURL(ctx context.Context, target *url.URL) error {
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.Allowed(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host %q", host)
+	}
+	for _, addr := range addrs {
+		if !g.Allowed(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that refuses to connect to addresses that
+// are not allowed. The check is made on the address being dialed, after
+// resolution, so that a host resolving to another address since its
+// registration or a redirect cannot get through. Proxies are not used, as
+// they would dial in place of the client.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !g.Allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
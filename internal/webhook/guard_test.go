@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGuardAllowed(t *testing.T) {
+	guard, err := NewGuard(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := guard.Allowed(net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("Expected Allowed(%s) to be %v, got %v", tt.ip, tt.allowed, got)
+		}
+	}
+}
+
+func TestGuardAllowedNetworks(t *testing.T) {
+	guard, err := NewGuard([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !guard.Allowed(net.ParseIP("10.1.2.3")) {
+		t.Error("Expected an address of an allowed network to be allowed")
+	}
+	if guard.Allowed(net.ParseIP("192.168.1.1")) {
+		t.Error("Expected other private addresses to stay refused")
+	}
+
+	if _, err := NewGuard([]string{"10.0.0.0"}); err == nil {
+		t.Error("Expected an invalid network to be rejected")
+	}
+}
+
+func TestGuardCheckURL(t *testing.T) {
+	guard, _ := NewGuard(nil)
+	for _, raw := range []string{"http://127.0.0.1:8080/hook", "http://[::1]/hook", "http://169.254.169.254/latest/meta-data"} {
+		target, _ := url.Parse(raw)
+		if err := guard.CheckURL(context.Background(), target); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Expected %s to be refused, got %v", raw, err)
+		}
+	}
+}
+
+func TestGuardClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	guard, _ := NewGuard(nil)
+	_, err := guard.Client(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Expected the loopback server to be refused, got %v", err)
+	}
+
+	guard, _ = NewGuard([]string{"127.0.0.0/8"})
+	resp, err := guard.Client(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the allowed network to be reached, got %v", err)
+	}
+	resp.Body.Close()
+}
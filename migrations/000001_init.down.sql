@@ -1 +1,4 @@
-DROP TABLE IF EXISTS subscriptions;
\ No newline at end of file
+DROP INDEX IF EXISTS idx_subscriptions_dates;
+DROP INDEX IF EXISTS idx_subscriptions_service_name;
+DROP INDEX IF EXISTS idx_subscriptions_user_id;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reminders_status;
+DROP TABLE IF EXISTS reminders;
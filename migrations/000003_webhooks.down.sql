@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP INDEX IF EXISTS idx_outbox_events_undispatched;
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    services TEXT[] NOT NULL DEFAULT '{}',
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, category)
+);
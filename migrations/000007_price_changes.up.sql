@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS price_changes (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    effective_date DATE NOT NULL,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (subscription_id, effective_date)
+);
@@ -0,0 +1,31 @@
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+ALTER TABLE outbox_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE outbox_events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+ALTER TABLE webhooks NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhooks DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON budgets;
+ALTER TABLE budgets NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE budgets DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON price_changes;
+ALTER TABLE price_changes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE price_changes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_tenant_id_user_id_category_key;
+ALTER TABLE budgets ADD CONSTRAINT budgets_user_id_category_key UNIQUE (user_id, category);
+
+DROP INDEX IF EXISTS idx_webhooks_tenant;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user;
+
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE price_changes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,45 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE price_changes ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user ON subscriptions (tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant ON webhooks (tenant_id);
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_user_id_category_key;
+ALTER TABLE budgets ADD CONSTRAINT budgets_tenant_id_user_id_category_key UNIQUE (tenant_id, user_id, category);
+
+-- Row-level security applies to connections that set app.tenant_id, which
+-- the service does when tenancy.row_level_security is enabled. Background
+-- workers act for every tenant and leave it unset. Superusers and table
+-- owners with BYPASSRLS are not subject to these policies.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE price_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE price_changes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON price_changes
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE budgets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE budgets FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON budgets
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON webhooks
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON outbox_events
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
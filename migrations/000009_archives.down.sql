@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS archived_totals;
+DROP TABLE IF EXISTS archives;
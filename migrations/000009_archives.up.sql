@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS archives (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name TEXT NOT NULL UNIQUE,
+    subscriptions INTEGER NOT NULL,
+    ended_before DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    restored_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_archives_tenant_created ON archives (tenant_id, created_at);
+
+-- Monthly sums of the archived subscriptions, so that totals still cover
+-- them: active_total is the price of those active in the month, started_total
+-- that of those that started in it.
+CREATE TABLE IF NOT EXISTS archived_totals (
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    month DATE NOT NULL,
+    active_total BIGINT NOT NULL DEFAULT 0,
+    started_total BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, user_id, service_name, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_archived_totals_tenant_month ON archived_totals (tenant_id, month);
+
+ALTER TABLE archives ENABLE ROW LEVEL SECURITY;
+ALTER TABLE archives FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON archives
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE archived_totals ENABLE ROW LEVEL SECURITY;
+ALTER TABLE archived_totals FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON archived_totals
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_events_tenant_user;
+DROP TABLE IF EXISTS erasures;
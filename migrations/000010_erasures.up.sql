@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS erasures (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    receipt JSONB NOT NULL,
+    erased_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasures_tenant_user ON erasures (tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_tenant_user ON outbox_events (tenant_id, user_id);
+
+ALTER TABLE erasures ENABLE ROW LEVEL SECURITY;
+ALTER TABLE erasures FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON erasures
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
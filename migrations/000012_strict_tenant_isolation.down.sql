@@ -0,0 +1,54 @@
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+ALTER TABLE webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON reminders;
+ALTER TABLE reminders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE reminders DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON price_changes;
+CREATE POLICY tenant_isolation ON price_changes
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON budgets;
+CREATE POLICY tenant_isolation ON budgets
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+CREATE POLICY tenant_isolation ON webhooks
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+CREATE POLICY tenant_isolation ON outbox_events
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON archives;
+CREATE POLICY tenant_isolation ON archives
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON archived_totals;
+CREATE POLICY tenant_isolation ON archived_totals
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON erasures;
+CREATE POLICY tenant_isolation ON erasures
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON bulk_operations;
+CREATE POLICY tenant_isolation ON bulk_operations
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE reminders DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,8 @@
+-- Event ids are taken when a transaction inserts the event, not when it
+-- commits, so a reader following ids can pass over an event that commits
+-- late. The id of the inserting transaction orders events by commit
+-- horizon instead: once no transaction older than a given one is running,
+-- no event can appear before it any more.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_txid ON outbox_events (txid, id);
@@ -0,0 +1,3 @@
+-- Hash of the ids of the subscriptions a bulk operation matched at its
+-- preview, so that its execution can tell whether the same ones still match.
+ALTER TABLE bulk_operations ADD COLUMN IF NOT EXISTS matched_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_erasures_pending;
+
+DELETE FROM erasures WHERE status = 'pending';
+ALTER TABLE erasures ALTER COLUMN erased_at SET DEFAULT NOW();
+ALTER TABLE erasures ALTER COLUMN erased_at SET NOT NULL;
+ALTER TABLE erasures DROP COLUMN IF EXISTS started_at;
+ALTER TABLE erasures DROP COLUMN IF EXISTS archived_deleted;
+ALTER TABLE erasures DROP COLUMN IF EXISTS status;
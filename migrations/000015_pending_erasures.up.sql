@@ -0,0 +1,10 @@
+-- An erasure is recorded as pending before the archives are rewritten, so
+-- that one interrupted between the archives and the database is known and
+-- resumed by the next erasure of the same user.
+ALTER TABLE erasures ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'completed';
+ALTER TABLE erasures ADD COLUMN IF NOT EXISTS archived_deleted INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE erasures ADD COLUMN IF NOT EXISTS started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE erasures ALTER COLUMN erased_at DROP NOT NULL;
+ALTER TABLE erasures ALTER COLUMN erased_at DROP DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_erasures_pending ON erasures (tenant_id, user_id) WHERE status = 'pending';